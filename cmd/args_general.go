@@ -85,4 +85,24 @@ func init() {
 		}
 		viper.SetDefault(key, defaultValue)
 	}
+
+	{
+		const (
+			key          = keys.WatchConfig
+			longOpt      = "watch-config"
+			envVar       = release.ENVPREFIX + "_WATCH_CONFIG"
+			description  = "Reload configuration when the config file changes (SIGHUP always reloads)"
+			defaultValue = defaults.WatchConfig
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 }
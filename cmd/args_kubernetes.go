@@ -150,6 +150,215 @@ func init() {
 		viper.SetDefault(key, defaultValue)
 	}
 
+	{
+		const (
+			key          = keys.K8SEventsCheckpointFile
+			longOpt      = "k8s-events-checkpoint-file"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_CHECKPOINT_FILE"
+			description  = "Kubernetes file to persist the event watcher checkpoint to, events seen before the checkpoint are not resubmitted on restart"
+			defaultValue = defaults.K8SEventsCheckpointFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsCheckpointConfigMap
+			longOpt      = "k8s-events-checkpoint-configmap"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_CHECKPOINT_CONFIGMAP"
+			description  = "Kubernetes configmap (in the agent namespace) to persist the event watcher checkpoint to"
+			defaultValue = defaults.K8SEventsCheckpointConfigMap
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsInclude
+			longOpt      = "k8s-events-include"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_INCLUDE"
+			description  = "Kubernetes events to collect, comma separated field=pattern (fields: namespace, type, reason, kind) (blank=all)"
+			defaultValue = defaults.K8SEventsInclude
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsExclude
+			longOpt      = "k8s-events-exclude"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_EXCLUDE"
+			description  = "Kubernetes events to drop, comma separated field=pattern (fields: namespace, type, reason, kind)"
+			defaultValue = defaults.K8SEventsExclude
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotations
+			longOpt      = "k8s-events-annotations"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATIONS"
+			description  = "Kubernetes create Circonus annotations for events"
+			defaultValue = defaults.K8SEventsAnnotations
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationMatch
+			longOpt      = "k8s-events-annotation-match"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_MATCH"
+			description  = "Kubernetes events to annotate, comma separated field=pattern (fields: namespace, type, reason, kind), events matching any are annotated (blank=all)"
+			defaultValue = defaults.K8SEventsAnnotationMatch
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationTitle
+			longOpt      = "k8s-events-annotation-title"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_TITLE"
+			description  = "Kubernetes event annotation title template (fields: Cluster, Namespace, Name, Kind, Type, Reason, Message, Source, Host, Count)"
+			defaultValue = defaults.K8SEventsAnnotationTitle
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationCategory
+			longOpt      = "k8s-events-annotation-category"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_CATEGORY"
+			description  = "Kubernetes event annotation category template"
+			defaultValue = defaults.K8SEventsAnnotationCategory
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationDescription
+			longOpt      = "k8s-events-annotation-description"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_DESCRIPTION"
+			description  = "Kubernetes event annotation description template"
+			defaultValue = defaults.K8SEventsAnnotationDescription
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationWindow
+			longOpt      = "k8s-events-annotation-window"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_WINDOW"
+			description  = "Kubernetes events with the same annotation title and category within the window are aggregated into one annotation"
+			defaultValue = defaults.K8SEventsAnnotationWindow
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEventsAnnotationRateLimit
+			longOpt      = "k8s-events-annotation-rate-limit"
+			envVar       = release.ENVPREFIX + "_K8S_EVENTS_ANNOTATION_RATE_LIMIT"
+			description  = "Kubernetes maximum event annotations created per minute, others are dropped (0=unlimited)"
+			defaultValue = defaults.K8SEventsAnnotationRateLimit
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
 	{
 		const (
 			key          = keys.K8SEnableKubeStateMetrics
@@ -174,7 +383,7 @@ func init() {
 			key          = keys.K8SEnableMetricsServer
 			longOpt      = "k8s-enable-metrics-server"
 			envVar       = release.ENVPREFIX + "_K8S_ENABLE_METRICS_SERVER"
-			description  = "Kubernetes enable collection from metrics-server"
+			description  = "Kubernetes enable collection of node, pod, and container resource usage from metrics-server (metrics.k8s.io)"
 			defaultValue = defaults.K8SEnableMetricsServer
 		)
 
@@ -190,11 +399,11 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SEnableNodes
-			longOpt      = "k8s-enable-nodes"
-			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODES"
-			description  = "Kubernetes include metrics for individual nodes"
-			defaultValue = defaults.K8SEnableNodes
+			key          = keys.K8SEnableAPIServer
+			longOpt      = "k8s-enable-api-server"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_API_SERVER"
+			description  = "Kubernetes enable collection of api server metrics (/metrics)"
+			defaultValue = defaults.K8SEnableAPIServer
 		)
 
 		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
@@ -209,14 +418,14 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SNodeSelector
-			longOpt      = "k8s-node-selector"
-			envVar       = release.ENVPREFIX + "_K8S_NODE_SELECTOR"
-			description  = "Kubernetes key:value node label selector expression"
-			defaultValue = defaults.K8SNodeSelector
+			key          = keys.K8SEnableControlPlane
+			longOpt      = "k8s-enable-control-plane"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CONTROL_PLANE"
+			description  = "Kubernetes enable control plane component (scheduler, controller-manager, etcd, coredns, kube-proxy) metrics"
+			defaultValue = defaults.K8SEnableControlPlane
 		)
 
-		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
 		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
@@ -228,11 +437,11 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SEnableNodeStats
-			longOpt      = "k8s-enable-node-stats"
-			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_STATS"
-			description  = "Kubernetes include summary stats for individual nodes (and pods)"
-			defaultValue = defaults.K8SEnableNodeStats
+			key          = keys.K8SEnableObjects
+			longOpt      = "k8s-enable-objects"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_OBJECTS"
+			description  = "Kubernetes enable built-in object state collection (kube-state-metrics compatible, without kube-state-metrics)"
+			defaultValue = defaults.K8SEnableObjects
 		)
 
 		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
@@ -247,11 +456,11 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SEnableNodeMetrics
-			longOpt      = "k8s-enable-node-metrics"
-			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_METRICS"
-			description  = "Kubernetes include metrics for individual nodes"
-			defaultValue = defaults.K8SEnableNodeMetrics
+			key          = keys.K8SEnablePrometheus
+			longOpt      = "k8s-enable-prometheus"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_PROMETHEUS"
+			description  = "Kubernetes enable scraping of pods and services annotated with prometheus.io/scrape"
+			defaultValue = defaults.K8SEnablePrometheus
 		)
 
 		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
@@ -266,14 +475,14 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SEnableCadvisorMetrics
-			longOpt      = "k8s-enable-cadvisor-metrics"
-			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CADVISOR_METRICS"
-			description  = "Kubernetes enable collection of kubelet cadvisor metrics"
-			defaultValue = defaults.K8SEnableCadvisorMetrics
+			key          = keys.K8SPrometheusAnnotationPrefix
+			longOpt      = "k8s-prometheus-annotation-prefix"
+			envVar       = release.ENVPREFIX + "_K8S_PROMETHEUS_ANNOTATION_PREFIX"
+			description  = "Kubernetes prefix of the prometheus scrape annotations (scrape, port, path, scheme)"
+			defaultValue = defaults.K8SPrometheusAnnotationPrefix
 		)
 
-		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
 		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
@@ -285,14 +494,14 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SIncludePods
-			longOpt      = "k8s-include-pods"
-			envVar       = release.ENVPREFIX + "_K8S_INCLUDE_PODS"
-			description  = "Kubernetes include metrics for individual pods"
-			defaultValue = defaults.K8SIncludePods
+			key          = keys.K8SPrometheusScrapeTimeout
+			longOpt      = "k8s-prometheus-scrape-timeout"
+			envVar       = release.ENVPREFIX + "_K8S_PROMETHEUS_SCRAPE_TIMEOUT"
+			description  = "Kubernetes timeout for scraping a prometheus annotated target"
+			defaultValue = defaults.K8SPrometheusScrapeTimeout
 		)
 
-		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
 		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
@@ -304,14 +513,14 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SPodLabelKey
-			longOpt      = "k8s-pod-label-key"
-			envVar       = release.ENVPREFIX + "_K8S_POD_LABEL_KEY"
-			description  = "Include pods with label"
-			defaultValue = defaults.K8SPodLabelKey
+			key          = keys.K8SPrometheusConcurrency
+			longOpt      = "k8s-prometheus-concurrency"
+			envVar       = release.ENVPREFIX + "_K8S_PROMETHEUS_CONCURRENCY"
+			description  = "Kubernetes maximum prometheus annotated targets scraped concurrently"
+			defaultValue = defaults.K8SPrometheusConcurrency
 		)
 
-		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
 		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
@@ -323,11 +532,30 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SPodLabelVal
-			longOpt      = "k8s-pod-label-val"
-			envVar       = release.ENVPREFIX + "_K8S_POD_LABEL_VAL"
-			description  = "Include pods with pod label and matching value"
-			defaultValue = defaults.K8SPodLabelVal
+			key          = keys.K8SEnableNodes
+			longOpt      = "k8s-enable-nodes"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODES"
+			description  = "Kubernetes include metrics for individual nodes"
+			defaultValue = defaults.K8SEnableNodes
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeSelector
+			longOpt      = "k8s-node-selector"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_SELECTOR"
+			description  = "Kubernetes key:value node label selector expression"
+			defaultValue = defaults.K8SNodeSelector
 		)
 
 		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
@@ -342,11 +570,11 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SIncludeContainers
-			longOpt      = "k8s-include-containers"
-			envVar       = release.ENVPREFIX + "_K8S_INCLUDE_CONTAINERS"
-			description  = "Kubernetes include metrics for individual containers"
-			defaultValue = defaults.K8SIncludeContainers
+			key          = keys.K8SEnableNodeStats
+			longOpt      = "k8s-enable-node-stats"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_STATS"
+			description  = "Kubernetes include summary stats for individual nodes (and pods)"
+			defaultValue = defaults.K8SEnableNodeStats
 		)
 
 		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
@@ -361,14 +589,14 @@ func init() {
 
 	{
 		const (
-			key         = keys.K8SNodePoolSize
-			longOpt     = "k8s-pool-size"
-			envVar      = release.ENVPREFIX + "_K8S_POOL_SIZE"
-			description = "Kubernetes node collector pool size"
+			key          = keys.K8SEnableNodeMetrics
+			longOpt      = "k8s-enable-node-metrics"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_NODE_METRICS"
+			description  = "Kubernetes include metrics for individual nodes"
+			defaultValue = defaults.K8SEnableNodeMetrics
 		)
-		defaultValue := uint(defaults.K8SNodePoolSize)
 
-		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
 		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
 			bindFlagError(longOpt, err)
 		}
@@ -380,11 +608,1075 @@ func init() {
 
 	{
 		const (
-			key          = keys.K8SAPITimelimit
-			longOpt      = "k8s-api-timelimit"
-			envVar       = release.ENVPREFIX + "_K8S_API_TIMELIMIT"
-			description  = "Kubernetes API request timelimit"
-			defaultValue = defaults.K8SAPITimelimit
+			key          = keys.K8SEnableCadvisorMetrics
+			longOpt      = "k8s-enable-cadvisor-metrics"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_CADVISOR_METRICS"
+			description  = "Kubernetes enable collection of kubelet cadvisor metrics"
+			defaultValue = defaults.K8SEnableCadvisorMetrics
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableResourceMetrics
+			longOpt      = "k8s-enable-resource-metrics"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_RESOURCE_METRICS"
+			description  = "Kubernetes enable collection of kubelet resource metrics (lightweight alternative to node stats)"
+			defaultValue = defaults.K8SEnableResourceMetrics
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableProbeMetrics
+			longOpt      = "k8s-enable-probe-metrics"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_PROBE_METRICS"
+			description  = "Kubernetes enable collection of kubelet probe metrics"
+			defaultValue = defaults.K8SEnableProbeMetrics
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIncludePods
+			longOpt      = "k8s-include-pods"
+			envVar       = release.ENVPREFIX + "_K8S_INCLUDE_PODS"
+			description  = "Kubernetes include metrics for individual pods"
+			defaultValue = defaults.K8SIncludePods
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPodLabelKey
+			longOpt      = "k8s-pod-label-key"
+			envVar       = release.ENVPREFIX + "_K8S_POD_LABEL_KEY"
+			description  = "Include pods with label"
+			defaultValue = defaults.K8SPodLabelKey
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPodLabelVal
+			longOpt      = "k8s-pod-label-val"
+			envVar       = release.ENVPREFIX + "_K8S_POD_LABEL_VAL"
+			description  = "Include pods with pod label and matching value"
+			defaultValue = defaults.K8SPodLabelVal
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SIncludeContainers
+			longOpt      = "k8s-include-containers"
+			envVar       = release.ENVPREFIX + "_K8S_INCLUDE_CONTAINERS"
+			description  = "Kubernetes include metrics for individual containers"
+			defaultValue = defaults.K8SIncludeContainers
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key         = keys.K8SNodePoolSize
+			longOpt     = "k8s-pool-size"
+			envVar      = release.ENVPREFIX + "_K8S_POOL_SIZE"
+			description = "Kubernetes node collector pool size"
+		)
+		defaultValue := uint(defaults.K8SNodePoolSize)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SAPITimelimit
+			longOpt      = "k8s-api-timelimit"
+			envVar       = release.ENVPREFIX + "_K8S_API_TIMELIMIT"
+			description  = "Kubernetes API request timelimit"
+			defaultValue = defaults.K8SAPITimelimit
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodesInterval
+			longOpt      = "k8s-nodes-interval"
+			envVar       = release.ENVPREFIX + "_K8S_NODES_INTERVAL"
+			description  = "Kubernetes node collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SNodesInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodesOffset
+			longOpt      = "k8s-nodes-offset"
+			envVar       = release.ENVPREFIX + "_K8S_NODES_OFFSET"
+			description  = "Kubernetes delay before first node collection"
+			defaultValue = defaults.K8SNodesOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMInterval
+			longOpt      = "k8s-ksm-interval"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_INTERVAL"
+			description  = "Kubernetes kube-state-metrics collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SKSMInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMOffset
+			longOpt      = "k8s-ksm-offset"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_OFFSET"
+			description  = "Kubernetes delay before first kube-state-metrics collection"
+			defaultValue = defaults.K8SKSMOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMNamespace
+			longOpt      = "k8s-ksm-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_NAMESPACE"
+			description  = "Kubernetes kube-state-metrics service namespace (blank=all namespaces)"
+			defaultValue = defaults.K8SKSMNamespace
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMService
+			longOpt      = "k8s-ksm-service"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_SERVICE"
+			description  = "Kubernetes kube-state-metrics service name (blank=any, use selector)"
+			defaultValue = defaults.K8SKSMService
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMSelector
+			longOpt      = "k8s-ksm-selector"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_SELECTOR"
+			description  = "Kubernetes kube-state-metrics service label selector"
+			defaultValue = defaults.K8SKSMSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMMetricsPort
+			longOpt      = "k8s-ksm-metrics-port"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_METRICS_PORT"
+			description  = "Kubernetes kube-state-metrics metrics service port name or number"
+			defaultValue = defaults.K8SKSMMetricsPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKSMTelemetryPort
+			longOpt      = "k8s-ksm-telemetry-port"
+			envVar       = release.ENVPREFIX + "_K8S_KSM_TELEMETRY_PORT"
+			description  = "Kubernetes kube-state-metrics telemetry service port name or number (blank=disabled)"
+			defaultValue = defaults.K8SKSMTelemetryPort
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SMSInterval
+			longOpt      = "k8s-ms-interval"
+			envVar       = release.ENVPREFIX + "_K8S_MS_INTERVAL"
+			description  = "Kubernetes metrics-server collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SMSInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SMSOffset
+			longOpt      = "k8s-ms-offset"
+			envVar       = release.ENVPREFIX + "_K8S_MS_OFFSET"
+			description  = "Kubernetes delay before first metrics-server collection"
+			defaultValue = defaults.K8SMSOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SAPIServerInterval
+			longOpt      = "k8s-api-server-interval"
+			envVar       = release.ENVPREFIX + "_K8S_API_SERVER_INTERVAL"
+			description  = "Kubernetes api server metrics collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SAPIServerInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SControlPlaneInterval
+			longOpt      = "k8s-control-plane-interval"
+			envVar       = release.ENVPREFIX + "_K8S_CONTROL_PLANE_INTERVAL"
+			description  = "Kubernetes control plane collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SControlPlaneInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SAPIServerOffset
+			longOpt      = "k8s-api-server-offset"
+			envVar       = release.ENVPREFIX + "_K8S_API_SERVER_OFFSET"
+			description  = "Kubernetes delay before first api server metrics collection"
+			defaultValue = defaults.K8SAPIServerOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SControlPlaneOffset
+			longOpt      = "k8s-control-plane-offset"
+			envVar       = release.ENVPREFIX + "_K8S_CONTROL_PLANE_OFFSET"
+			description  = "Kubernetes delay before first control plane collection"
+			defaultValue = defaults.K8SControlPlaneOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdCertFile
+			longOpt      = "k8s-etcd-cert-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_CERT_FILE"
+			description  = "Kubernetes etcd client certificate for control plane collection (blank=etcd not collected)"
+			defaultValue = defaults.K8SEtcdCertFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdKeyFile
+			longOpt      = "k8s-etcd-key-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_KEY_FILE"
+			description  = "Kubernetes etcd client key for control plane collection"
+			defaultValue = defaults.K8SEtcdKeyFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEtcdCAFile
+			longOpt      = "k8s-etcd-ca-file"
+			envVar       = release.ENVPREFIX + "_K8S_ETCD_CA_FILE"
+			description  = "Kubernetes etcd CA certificate for control plane collection"
+			defaultValue = defaults.K8SEtcdCAFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SObjectsInterval
+			longOpt      = "k8s-objects-interval"
+			envVar       = release.ENVPREFIX + "_K8S_OBJECTS_INTERVAL"
+			description  = "Kubernetes object state collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SObjectsInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPrometheusInterval
+			longOpt      = "k8s-prometheus-interval"
+			envVar       = release.ENVPREFIX + "_K8S_PROMETHEUS_INTERVAL"
+			description  = "Kubernetes prometheus annotated target collection interval (blank=k8s-interval)"
+			defaultValue = defaults.K8SPrometheusInterval
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SObjectsOffset
+			longOpt      = "k8s-objects-offset"
+			envVar       = release.ENVPREFIX + "_K8S_OBJECTS_OFFSET"
+			description  = "Kubernetes delay before first object state collection"
+			defaultValue = defaults.K8SObjectsOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPrometheusOffset
+			longOpt      = "k8s-prometheus-offset"
+			envVar       = release.ENVPREFIX + "_K8S_PROMETHEUS_OFFSET"
+			description  = "Kubernetes delay before first prometheus annotated target collection"
+			defaultValue = defaults.K8SPrometheusOffset
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableLeaderElection
+			longOpt      = "k8s-enable-leader-election"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_LEADER_ELECTION"
+			description  = "Kubernetes enable leader election, only the leader collects metrics (run multiple replicas)"
+			defaultValue = defaults.K8SEnableLeaderElection
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLeaderElectionName
+			longOpt      = "k8s-leader-election-name"
+			envVar       = release.ENVPREFIX + "_K8S_LEADER_ELECTION_NAME"
+			description  = "Kubernetes leader election lease name"
+			defaultValue = defaults.K8SLeaderElectionName
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLeaderElectionNS
+			longOpt      = "k8s-leader-election-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_LEADER_ELECTION_NAMESPACE"
+			description  = "Kubernetes leader election lease namespace (blank=agent namespace)"
+			defaultValue = defaults.K8SLeaderElectionNS
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SAlignInterval
+			longOpt      = "k8s-align-interval"
+			envVar       = release.ENVPREFIX + "_K8S_ALIGN_INTERVAL"
+			description  = "Align collection starts to wall clock boundaries of the interval (e.g. :00, :30)"
+			defaultValue = defaults.K8SAlignInterval
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SJitter
+			longOpt      = "k8s-jitter"
+			envVar       = release.ENVPREFIX + "_K8S_JITTER"
+			description  = "Maximum random delay added to collection starts, to spread a fleet of agents (e.g. 5s)"
+			defaultValue = defaults.K8SJitter
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SOverrunPolicy
+			longOpt      = "k8s-overrun-policy"
+			envVar       = release.ENVPREFIX + "_K8S_OVERRUN_POLICY"
+			description  = "Policy when a collection is still running when the next one is due [(skip|queue|cancel)]"
+			defaultValue = defaults.K8SOverrunPolicy
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SEnableSharding
+			longOpt      = "k8s-enable-sharding"
+			envVar       = release.ENVPREFIX + "_K8S_ENABLE_SHARDING"
+			description  = "Split node collection across agent replicas, cluster level collectors run on one replica"
+			defaultValue = defaults.K8SEnableSharding
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SShardMembership
+			longOpt      = "k8s-shard-membership"
+			envVar       = release.ENVPREFIX + "_K8S_SHARD_MEMBERSHIP"
+			description  = "How shard members are determined [(lease|statefulset)]"
+			defaultValue = defaults.K8SShardMembership
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SShardGroup
+			longOpt      = "k8s-shard-group"
+			envVar       = release.ENVPREFIX + "_K8S_SHARD_GROUP"
+			description  = "Shard group name (labels member leases, or the statefulset name)"
+			defaultValue = defaults.K8SShardGroup
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SShardNS
+			longOpt      = "k8s-shard-namespace"
+			envVar       = release.ENVPREFIX + "_K8S_SHARD_NAMESPACE"
+			description  = "Namespace of shard member leases or statefulset (blank=agent namespace)"
+			defaultValue = defaults.K8SShardNS
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SShardReplicas
+			longOpt      = "k8s-shard-replicas"
+			envVar       = release.ENVPREFIX + "_K8S_SHARD_REPLICAS"
+			description  = "Number of statefulset replicas (0=read from statefulset spec)"
+			defaultValue = defaults.K8SShardReplicas
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeletMode
+			longOpt      = "k8s-kubelet-mode"
+			envVar       = release.ENVPREFIX + "_K8S_KUBELET_MODE"
+			description  = "How to reach kubelets, via api server proxy or directly (falls back to proxy) [(proxy|direct)]"
+			defaultValue = defaults.K8SKubeletMode
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeletPort
+			longOpt      = "k8s-kubelet-port"
+			envVar       = release.ENVPREFIX + "_K8S_KUBELET_PORT"
+			description  = "Kubelet port for direct mode (0=node kubelet endpoint port)"
+			defaultValue = defaults.K8SKubeletPort
+		)
+
+		rootCmd.PersistentFlags().Uint(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeletCAFile
+			longOpt      = "k8s-kubelet-ca-file"
+			envVar       = release.ENVPREFIX + "_K8S_KUBELET_CA_FILE"
+			description  = "Kubelet CA cert file for direct mode (blank=api CA cert file)"
+			defaultValue = defaults.K8SKubeletCAFile
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SKubeletInsecure
+			longOpt      = "k8s-kubelet-insecure-skip-verify"
+			envVar       = release.ENVPREFIX + "_K8S_KUBELET_INSECURE_SKIP_VERIFY"
+			description  = "Skip verification of kubelet certificates in direct mode"
+			defaultValue = defaults.K8SKubeletInsecure
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SLocalNode
+			longOpt      = "k8s-local-node"
+			envVar       = release.ENVPREFIX + "_K8S_LOCAL_NODE"
+			description  = "Collect only the node the agent is running on (daemonset deployment)"
+			defaultValue = defaults.K8SLocalNode
+		)
+
+		rootCmd.PersistentFlags().Bool(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNodeName
+			longOpt      = "k8s-node-name"
+			envVar       = release.ENVPREFIX + "_K8S_NODE_NAME"
+			description  = "Name of the node the agent is running on (blank=NODE_NAME env var)"
+			defaultValue = defaults.K8SNodeName
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SPodSelector
+			longOpt      = "k8s-pod-selector"
+			envVar       = release.ENVPREFIX + "_K8S_POD_SELECTOR"
+			description  = "Include pods matching label selector (e.g. 'app in (web,api),tier!=cache')"
+			defaultValue = defaults.K8SPodSelector
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNamespaceInclude
+			longOpt      = "k8s-namespace-include"
+			envVar       = release.ENVPREFIX + "_K8S_NAMESPACE_INCLUDE"
+			description  = "Include only these namespaces (comma separated)"
+			defaultValue = defaults.K8SNamespaceInclude
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SNamespaceExclude
+			longOpt      = "k8s-namespace-exclude"
+			envVar       = release.ENVPREFIX + "_K8S_NAMESPACE_EXCLUDE"
+			description  = "Exclude these namespaces (comma separated)"
+			defaultValue = defaults.K8SNamespaceExclude
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCollectAnnotation
+			longOpt      = "k8s-collect-annotation"
+			envVar       = release.ENVPREFIX + "_K8S_COLLECT_ANNOTATION"
+			description  = "Pods and namespaces with this annotation set to false are not collected (blank=disabled)"
+			defaultValue = defaults.K8SCollectAnnotation
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STagAllow
+			longOpt      = "k8s-tag-allow"
+			envVar       = release.ENVPREFIX + "_K8S_TAG_ALLOW"
+			description  = "Label key patterns to turn into stream tags, glob or /regex/ (comma separated, blank=all)"
+			defaultValue = defaults.K8STagAllow
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STagDeny
+			longOpt      = "k8s-tag-deny"
+			envVar       = release.ENVPREFIX + "_K8S_TAG_DENY"
+			description  = "Label key patterns to drop, glob or /regex/ (comma separated)"
+			defaultValue = defaults.K8STagDeny
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8STagRename
+			longOpt      = "k8s-tag-rename"
+			envVar       = release.ENVPREFIX + "_K8S_TAG_RENAME"
+			description  = "Label key renames, from=to (comma separated)"
+			defaultValue = defaults.K8STagRename
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCounterRates
+			longOpt      = "k8s-counter-rates"
+			envVar       = release.ENVPREFIX + "_K8S_COUNTER_RATES"
+			description  = "Counter metric name patterns to emit per second rates for (comma separated globs or /regex/, emitted as <name>_rate)"
+			defaultValue = defaults.K8SCounterRates
+		)
+
+		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(longOpt)); err != nil {
+			bindFlagError(longOpt, err)
+		}
+		if err := viper.BindEnv(key, envVar); err != nil {
+			bindEnvError(envVar, err)
+		}
+		viper.SetDefault(key, defaultValue)
+	}
+
+	{
+		const (
+			key          = keys.K8SCounterDeltas
+			longOpt      = "k8s-counter-deltas"
+			envVar       = release.ENVPREFIX + "_K8S_COUNTER_DELTAS"
+			description  = "Counter metric name patterns to emit deltas for (comma separated globs or /regex/, emitted as <name>_delta)"
+			defaultValue = defaults.K8SCounterDeltas
 		)
 
 		rootCmd.PersistentFlags().String(longOpt, defaultValue, envDescription(description, envVar))
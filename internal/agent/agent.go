@@ -13,13 +13,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cluster"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
-	"github.com/pkg/errors"
+	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -31,83 +31,80 @@ type Agent struct {
 	group       *errgroup.Group
 	groupCtx    context.Context
 	groupCancel context.CancelFunc
-	clusters    map[string]*cluster.Cluster
+	clusters    map[string]*runningCluster
+	circCfg     config.Circonus
+	clustersmu  sync.Mutex
 	signalCh    chan os.Signal
+	reloadCh    chan struct{}
+	reload      reloadState
 	logger      zerolog.Logger
 }
 
+// reloadState tracks the reload running on its own goroutine
+type reloadState struct {
+	running bool
+	pending bool // requested while running, reload again when done
+	sync.Mutex
+}
+
+// runningCluster is a cluster and the configuration it was created with
+type runningCluster struct {
+	cluster *cluster.Cluster
+	cfg     config.Cluster
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
 // New returns a new agent instance
 func New() (*Agent, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	g, gctx := errgroup.WithContext(ctx)
 
-	var err error
 	a := Agent{
 		group:       g,
 		groupCtx:    gctx,
 		groupCancel: cancel,
-		clusters:    make(map[string]*cluster.Cluster),
+		clusters:    make(map[string]*runningCluster),
 		signalCh:    make(chan os.Signal, 10),
+		reloadCh:    make(chan struct{}, 1),
 		logger:      log.With().Str("pkg", "agent").Logger(),
 	}
 
-	err = config.Validate()
-	if err != nil {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
 
-	var cfg *config.Config
-
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, errors.Wrap(err, "parsing config")
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	// Set the hidden settings based on viper
-	cfg.Circonus.ConcurrentSubmissions = defaults.ConcurrentSubmissions
-	cfg.Circonus.SerialSubmissions = defaults.SerialSubmissions
-	if viper.GetBool(keys.SerialSubmissions) != defaults.SerialSubmissions {
-		cfg.Circonus.SerialSubmissions = true
-		cfg.Circonus.ConcurrentSubmissions = false
-	}
-	cfg.Circonus.MaxMetricBucketSize = defaults.MaxMetricBucketSize
-	if viper.GetUint(keys.MaxMetricBucketSize) != defaults.MaxMetricBucketSize {
-		cfg.Circonus.MaxMetricBucketSize = viper.GetInt(keys.MaxMetricBucketSize)
-	}
-	cfg.Circonus.Base64Tags = defaults.Base64Tags
-	if viper.GetBool(keys.NoBase64) {
-		cfg.Circonus.Base64Tags = false
-	}
-	cfg.Circonus.UseGZIP = defaults.UseGZIP
-	if viper.GetBool(keys.NoGZIP) {
-		cfg.Circonus.UseGZIP = false
-	}
-	cfg.Circonus.DryRun = viper.GetBool(keys.DryRun)
-	// cfg.Circonus.StreamMetrics = viper.GetBool(keys.StreamMetrics)
-	cfg.Circonus.DebugSubmissions = viper.GetBool(keys.DebugSubmissions)
-
-	if len(cfg.Clusters) > 0 { // multiple clusters
-		for _, clusterConfig := range cfg.Clusters {
-			clusterConfig := clusterConfig
-			c, err := cluster.New(clusterConfig, cfg.Circonus, a.logger)
-			if err != nil {
-				a.logger.Error().Err(err).Msg("configuring cluster, skipping...")
-				continue
-			}
-			a.clusters[clusterConfig.Name] = c
-		}
-	} else { // single cluster
-		c, err := cluster.New(cfg.Kubernetes, cfg.Circonus, a.logger)
+	a.circCfg = cfg.Circonus
+	for _, clusterConfig := range clusterConfigs(cfg) {
+		c, err := cluster.New(clusterConfig, cfg.Circonus, a.logger)
 		if err != nil {
-			a.logger.Error().Err(err).Msg("configuring cluster")
-		} else {
-			a.clusters[cfg.Kubernetes.Name] = c
+			a.logger.Error().Err(err).Str("cluster", clusterConfig.Name).Msg("configuring cluster, skipping...")
+			continue
 		}
+		a.clusters[clusterConfig.Name] = &runningCluster{cluster: c, cfg: clusterConfig}
 	}
 
 	if len(a.clusters) == 0 {
 		log.Fatal().Msg("no cluster(s) initialized")
 	}
 
+	if viper.GetBool(keys.WatchConfig) {
+		if f := viper.ConfigFileUsed(); f != "" {
+			viper.OnConfigChange(func(e fsnotify.Event) {
+				a.logger.Info().Str("file", e.Name).Str("op", e.Op.String()).Msg("config file changed")
+				a.requestReload()
+			})
+			viper.WatchConfig()
+		} else {
+			a.logger.Warn().Msg("no config file in use, ignoring watch config")
+		}
+	}
+
 	a.signalNotifySetup()
 
 	go func() {
@@ -143,12 +140,11 @@ func (a *Agent) Start() error {
 
 	a.group.Go(a.handleSignals)
 
+	a.clustersmu.Lock()
 	for id := range a.clusters {
-		id := id
-		a.group.Go(func() error {
-			return a.clusters[id].Start(a.groupCtx)
-		})
+		a.startCluster(a.clusters[id])
 	}
+	a.clustersmu.Unlock()
 
 	log.Debug().
 		Int("pid", os.Getpid()).
@@ -5,8 +5,62 @@
 
 package agent
 
-import "testing"
+import (
+	"reflect"
+	"testing"
 
-func Test(t *testing.T) {
-	t.Log("Placeholder...nothing to test currently")
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestDiffClusters(t *testing.T) {
+	t.Log("Testing diffClusters")
+
+	running := map[string]config.Cluster{
+		"a": {Name: "a", Interval: "1m"},
+		"b": {Name: "b", Interval: "1m"},
+		"c": {Name: "c", Interval: "1m"},
+	}
+	circ := config.Circonus{DefaultStreamtags: "foo:bar"}
+
+	tests := []struct {
+		name       string
+		updated    map[string]config.Cluster
+		circ       config.Circonus
+		expAdded   []string
+		expChanged []string
+		expRemoved []string
+	}{
+		{"no change", running, circ, nil, nil, nil},
+		{"add", map[string]config.Cluster{
+			"a": {Name: "a", Interval: "1m"},
+			"b": {Name: "b", Interval: "1m"},
+			"c": {Name: "c", Interval: "1m"},
+			"d": {Name: "d", Interval: "1m"},
+		}, circ, []string{"d"}, nil, nil},
+		{"change", map[string]config.Cluster{
+			"a": {Name: "a", Interval: "1m"},
+			"b": {Name: "b", Interval: "30s"},
+			"c": {Name: "c", Interval: "1m"},
+		}, circ, nil, []string{"b"}, nil},
+		{"remove", map[string]config.Cluster{
+			"a": {Name: "a", Interval: "1m"},
+		}, circ, nil, nil, []string{"b", "c"}},
+		{"circonus change", running, config.Circonus{DefaultStreamtags: "foo:baz"}, nil, []string{"a", "b", "c"}, nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			added, changed, removed := diffClusters(running, circ, test.updated, test.circ)
+			if !reflect.DeepEqual(added, test.expAdded) {
+				t.Fatalf("expected added %v, got %v", test.expAdded, added)
+			}
+			if !reflect.DeepEqual(changed, test.expChanged) {
+				t.Fatalf("expected changed %v, got %v", test.expChanged, changed)
+			}
+			if !reflect.DeepEqual(removed, test.expRemoved) {
+				t.Fatalf("expected removed %v, got %v", test.expRemoved, removed)
+			}
+		})
+	}
 }
@@ -0,0 +1,250 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package agent
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/cluster"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// loadConfig parses the viper configuration and applies the hidden settings
+func loadConfig() (*config.Config, error) {
+	var cfg *config.Config
+
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, errors.Wrap(err, "parsing config")
+	}
+
+	// Set the hidden settings based on viper
+	cfg.Circonus.ConcurrentSubmissions = defaults.ConcurrentSubmissions
+	cfg.Circonus.SerialSubmissions = defaults.SerialSubmissions
+	if viper.GetBool(keys.SerialSubmissions) != defaults.SerialSubmissions {
+		cfg.Circonus.SerialSubmissions = true
+		cfg.Circonus.ConcurrentSubmissions = false
+	}
+	cfg.Circonus.MaxMetricBucketSize = defaults.MaxMetricBucketSize
+	if viper.GetUint(keys.MaxMetricBucketSize) != defaults.MaxMetricBucketSize {
+		cfg.Circonus.MaxMetricBucketSize = viper.GetInt(keys.MaxMetricBucketSize)
+	}
+	cfg.Circonus.Base64Tags = defaults.Base64Tags
+	if viper.GetBool(keys.NoBase64) {
+		cfg.Circonus.Base64Tags = false
+	}
+	cfg.Circonus.UseGZIP = defaults.UseGZIP
+	if viper.GetBool(keys.NoGZIP) {
+		cfg.Circonus.UseGZIP = false
+	}
+	cfg.Circonus.DryRun = viper.GetBool(keys.DryRun)
+	// cfg.Circonus.StreamMetrics = viper.GetBool(keys.StreamMetrics)
+	cfg.Circonus.DebugSubmissions = viper.GetBool(keys.DebugSubmissions)
+
+	return cfg, nil
+}
+
+// clusterConfigs returns the configured clusters (multiple clusters or a single cluster)
+func clusterConfigs(cfg *config.Config) []config.Cluster {
+	if len(cfg.Clusters) > 0 {
+		return cfg.Clusters
+	}
+	return []config.Cluster{cfg.Kubernetes}
+}
+
+// diffClusters compares the running cluster configurations to a new set
+// of configurations, a change to the circonus configuration changes all clusters
+func diffClusters(
+	running map[string]config.Cluster,
+	runningCirc config.Circonus,
+	updated map[string]config.Cluster,
+	updatedCirc config.Circonus) (added, changed, removed []string) {
+
+	circChanged := !reflect.DeepEqual(runningCirc, updatedCirc)
+
+	for name, cfg := range updated {
+		cur, ok := running[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case circChanged || !reflect.DeepEqual(cur, cfg):
+			changed = append(changed, name)
+		}
+	}
+
+	for name := range running {
+		if _, ok := updated[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	return added, changed, removed
+}
+
+// requestReload queues a configuration reload, requests made
+// while a reload is already pending are coalesced
+func (a *Agent) requestReload() {
+	select {
+	case a.reloadCh <- struct{}{}:
+	default:
+	}
+}
+
+// startReload runs reload on its own goroutine so the signal handler
+// stays responsive while clusters are stopped and started. Only one
+// reload runs at a time, requests made while one is running result in
+// a single reload once it completes.
+func (a *Agent) startReload() {
+	a.reload.Lock()
+	if a.reload.running {
+		a.reload.pending = true
+		a.reload.Unlock()
+		return
+	}
+	a.reload.running = true
+	a.reload.Unlock()
+
+	go func() {
+		for {
+			a.reloadConfig()
+
+			a.reload.Lock()
+			if !a.reload.pending || a.groupCtx.Err() != nil {
+				a.reload.running = false
+				a.reload.pending = false
+				a.reload.Unlock()
+				return
+			}
+			a.reload.pending = false
+			a.reload.Unlock()
+		}
+	}()
+}
+
+// reloadConfig re-reads the configuration and stops, rebuilds, or starts
+// individual clusters. If the new configuration is invalid it is
+// rejected and the running clusters are left untouched.
+func (a *Agent) reloadConfig() {
+	a.logger.Info().Msg("reloading configuration")
+
+	if f := viper.ConfigFileUsed(); f != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			a.logger.Error().Err(err).Str("config_file", f).Msg("reading config, keeping running configuration")
+			return
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		a.logger.Error().Err(err).Msg("invalid config, keeping running configuration")
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		a.logger.Error().Err(err).Msg("invalid config, keeping running configuration")
+		return
+	}
+
+	updated := make(map[string]config.Cluster)
+	for _, clusterConfig := range clusterConfigs(cfg) {
+		if _, found := updated[clusterConfig.Name]; found {
+			a.logger.Error().Str("cluster", clusterConfig.Name).Msg("duplicate cluster name, keeping running configuration")
+			return
+		}
+		updated[clusterConfig.Name] = clusterConfig
+	}
+
+	a.clustersmu.Lock()
+	defer a.clustersmu.Unlock()
+
+	if a.groupCtx.Err() != nil {
+		a.logger.Info().Msg("agent stopping, reload abandoned")
+		return
+	}
+
+	running := make(map[string]config.Cluster)
+	for name, rc := range a.clusters {
+		running[name] = rc.cfg
+	}
+
+	added, changed, removed := diffClusters(running, a.circCfg, updated, cfg.Circonus)
+	if len(added)+len(changed)+len(removed) == 0 {
+		a.logger.Info().Msg("no configuration changes")
+		return
+	}
+
+	// build all new/changed clusters before touching the running
+	// ones, so a bad configuration does not stop anything
+	rebuilt := make(map[string]*cluster.Cluster)
+	for _, name := range append(added, changed...) {
+		c, err := cluster.New(updated[name], cfg.Circonus, a.logger)
+		if err != nil {
+			a.logger.Error().Err(err).Str("cluster", name).Msg("configuring cluster, keeping running configuration")
+			return
+		}
+		rebuilt[name] = c
+	}
+
+	// stopped clusters remove their leader election and sharding /stats entries
+	for _, name := range append(removed, changed...) {
+		a.stopCluster(a.clusters[name])
+		delete(a.clusters, name)
+		a.logger.Info().Str("cluster", name).Msg("stopped cluster")
+	}
+
+	if a.groupCtx.Err() != nil {
+		a.logger.Info().Msg("agent stopping, not starting reloaded clusters")
+		return
+	}
+
+	a.circCfg = cfg.Circonus
+	for _, name := range append(added, changed...) {
+		rc := &runningCluster{cluster: rebuilt[name], cfg: updated[name]}
+		a.clusters[name] = rc
+		a.startCluster(rc)
+		a.logger.Info().Str("cluster", name).Msg("started cluster")
+	}
+
+	if err := config.StatConfig(); err != nil {
+		a.logger.Warn().Err(err).Msg("updating config stats")
+	}
+
+	a.logger.Info().
+		Strs("added", added).
+		Strs("changed", changed).
+		Strs("removed", removed).
+		Msg("configuration reloaded")
+}
+
+// startCluster runs a cluster with its own context so it can be
+// stopped independently of the other clusters
+func (a *Agent) startCluster(rc *runningCluster) {
+	ctx, cancel := context.WithCancel(a.groupCtx)
+	rc.cancel = cancel
+	rc.done = make(chan struct{})
+	a.group.Go(func() error {
+		defer close(rc.done)
+		return rc.cluster.Start(ctx)
+	})
+}
+
+// stopCluster stops a running cluster and waits for it to finish
+func (a *Agent) stopCluster(rc *runningCluster) {
+	if rc == nil || rc.cancel == nil {
+		return
+	}
+	rc.cancel()
+	<-rc.done
+}
@@ -34,7 +34,9 @@ func (a *Agent) handleSignals() error {
 			switch sig {
 			case os.Interrupt, unix.SIGTERM:
 				a.Stop()
-			case unix.SIGPIPE, unix.SIGHUP:
+			case unix.SIGHUP:
+				a.requestReload()
+			case unix.SIGPIPE:
 				// Noop
 			case unix.SIGTRAP:
 				stacklen := runtime.Stack(buf, true)
@@ -42,6 +44,8 @@ func (a *Agent) handleSignals() error {
 			default:
 				log.Warn().Str("signal", sig.String()).Msg("unsupported")
 			}
+		case <-a.reloadCh:
+			a.startReload()
 		case <-a.groupCtx.Done():
 			return nil
 		}
@@ -0,0 +1,204 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package apiserver is the api server collector, it collects the
+// api server's own prometheus metrics (/metrics)
+package apiserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+type APIServer struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	running      bool
+	apiTimelimit time.Duration
+	deprecated   string          // deprecated setting that enabled the collector, tagged on the series
+	tags         *tagrules.Rules // nil=all labels become tags
+	rates        *rates.Store    // nil=no counter rates or deltas
+	sync.Mutex
+}
+
+func init() {
+	registry.Register("api-server", func(env registry.Env) (registry.Collector, error) {
+		api, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		api.deprecated = env.Options["deprecated"]
+		api.tags = env.Tags
+		api.rates = env.Rates
+		return api, nil
+	})
+}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*APIServer, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	api := &APIServer{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "api-server").Logger(),
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			api.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			api.apiTimelimit = v
+		}
+	}
+
+	if api.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			api.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		api.apiTimelimit = v
+	}
+
+	return api, nil
+}
+
+func (api *APIServer) ID() string {
+	return "api-server"
+}
+
+func (api *APIServer) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
+	api.Lock()
+	if api.running {
+		api.log.Warn().Msg("already running")
+		api.Unlock()
+		return
+	}
+	api.running = true
+	api.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			api.log.Error().Interface("panic", r).Msg("recover")
+			api.Lock()
+			api.running = false
+			api.Unlock()
+		}
+	}()
+
+	collectStart := time.Now()
+
+	metricsURL := api.config.URL + "/metrics"
+
+	client, err := k8s.NewAPIClient(tlsConfig, api.apiTimelimit)
+	if err != nil {
+		api.log.Error().Err(err).Str("url", metricsURL).Msg("metrics cli")
+		api.Lock()
+		api.running = false
+		api.Unlock()
+		return
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := k8s.NewAPIRequest(api.config.BearerToken, metricsURL)
+	if err != nil {
+		api.log.Error().Err(err).Str("url", metricsURL).Msg("metrics req")
+		api.Lock()
+		api.running = false
+		api.Unlock()
+		return
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		api.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		api.log.Error().Err(err).Str("url", metricsURL).Msg("metrics")
+		api.Lock()
+		api.running = false
+		api.Unlock()
+		return
+	}
+	defer resp.Body.Close()
+	api.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		api.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			api.log.Error().Err(err).Str("url", metricsURL).Msg("reading response")
+			return
+		}
+		api.log.Warn().Str("url", metricsURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return
+	}
+
+	streamTags := []string{
+		"source:api-server",
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	if api.deprecated != "" {
+		streamTags = append(streamTags, "deprecated:"+api.deprecated)
+	}
+	measurementTags := []string{}
+
+	// if api.check.StreamMetrics() {
+	// 	if err := promtext.StreamMetrics(ctx, api.check, api.log, resp.Body, streamTags, measurementTags, ts); err != nil {
+	// 		api.log.Error().Err(err).Msg("formatting metrics")
+	// 	}
+	// } else {
+	if err := promtext.QueueMetrics(ctx, api.check, api.log, resp.Body, streamTags, measurementTags, ts, &promtext.Options{Tags: api.tags, Rates: api.rates}); err != nil {
+		api.log.Error().Err(err).Msg("formatting metrics")
+	}
+	// }
+
+	api.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "opt", Value: "collect_api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	api.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("api-server collect end")
+	api.Lock()
+	api.running = false
+	api.Unlock()
+}
@@ -0,0 +1,12 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package apiserver
+
+import "testing"
+
+func Test(t *testing.T) {
+	t.Log("Placeholder...nothing to test currently")
+}
@@ -0,0 +1,38 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package circonus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+)
+
+// CreateAnnotation creates a Circonus annotation using the check's api client
+func (c *Check) CreateAnnotation(annotation *apiclient.Annotation) error {
+	if annotation == nil {
+		return errors.New("invalid annotation (nil)")
+	}
+
+	if c.client == nil {
+		if c.config.DryRun {
+			data, err := json.Marshal(annotation)
+			if err != nil {
+				return errors.Wrap(err, "encoding annotation")
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		return errors.New("no api client and not in dry-run mode")
+	}
+
+	if _, err := c.client.CreateAnnotation(annotation); err != nil {
+		return errors.Wrap(err, "creating annotation")
+	}
+	return nil
+}
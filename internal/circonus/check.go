@@ -49,6 +49,7 @@ type MetricSet struct {
 
 type Check struct {
 	config          *config.Circonus
+	client          *apiclient.API // nil=dry run
 	brokerTLSConfig *tls.Config
 	checkBundleCID  string
 	checkUUID       string
@@ -102,6 +103,7 @@ func NewCheck(parentLogger zerolog.Logger, cfg *config.Circonus) (*Check, error)
 	if err != nil {
 		return nil, errors.Wrap(err, "setting up circonus api client")
 	}
+	c.client = client
 
 	if err := c.initializeCheckBundle(client); err != nil {
 		return nil, err
@@ -343,6 +345,7 @@ func (c *Check) loadMetricFilters() [][]string {
 		{"allow", "^(used|capacity)$", "tags", "and(units:bytes,or(resource:memory,resource:fs,volume_name:*),not(container_name:*),not(sys_container:*))", "utilization"},
 		{"allow", "^usageNanoCores$", "tags", "and(not(container_name:*),not(sys_container:*))", "utilization"},
 		{"allow", "^kube_pod_container_status_(running|terminated|waiting|ready)$", "containers"},
+		{"allow", "^kube_pod_container_status_(restarts_total|waiting_reason)$", "containers"},
 		{"allow", "^kube_deployment_(created|spec_replicas|status_replicas|status_replicas_updated|status_replicas_available|status_replicas_unavailable)$", "deployments"},
 		{"allow", "^kube_pod_start_time", "pods"},
 		{"allow", "^kube_pod_status_phase$", "tags", "and(or(phase:Running,phase:Pending,phase:Failed,phase:Succeeded))", "pods"},
@@ -351,10 +354,28 @@ func (c *Check) loadMetricFilters() [][]string {
 		{"allow", "^(node|kubelet_running_pod_count|Ready)$", "nodes"},
 		{"allow", "^NetworkUnavailable$", "node status"},
 		{"allow", "^(Disk|Memory|PID)Pressure$", "node status"},
-		{"allow", "^capacity_.*$", "node capacity"},
+		{"allow", "^(condition|condition_transition_seconds|unschedulable|taints)$", "node conditions"},
+		{"allow", "^nodes_(ready|not_ready|unschedulable|skipped)$", "node counts"},
+		{"allow", "^(capacity|allocatable|reserved)_.*$", "node capacity"},
+		{"allow", "^(request|limit|usage_request_ratio|usage_limit_ratio)$", "resource requests and limits"},
+		{"allow", "^.+_(rate|delta)$", "counter rates and deltas"},
+		{"allow", "^(node|pod|container)_(cpu_usage_seconds_total|memory_working_set_bytes)$", "tags", "and(source_type:resource)", "kubelet resource metrics"},
+		{"allow", "^prober_probe_total$", "tags", "and(source_type:probes)", "kubelet probe results"},
+		{"allow", "^usage(_window)?$", "tags", "and(source:metrics-server,not(container_name:*))", "metrics-server resource usage"},
+		{"allow", "^scheduler_(pending_pods|schedule_attempts_total|preemption_attempts_total|e2e_scheduling_duration_seconds(_count|_sum)?)$", "tags", "and(component:kube-scheduler)", "control plane scheduler"},
+		{"allow", "^workqueue_(depth|adds_total|retries_total|queue_duration_seconds(_count|_sum)?)$", "tags", "and(component:kube-controller-manager)", "control plane controller-manager"},
+		{"allow", "^leader_election_master_status$", "tags", "and(or(component:kube-scheduler,component:kube-controller-manager))", "control plane leader election"},
+		{"allow", "^etcd_(server_has_leader|server_leader_changes_seen_total|server_proposals_failed_total|server_proposals_pending|mvcc_db_total_size_in_bytes|disk_wal_fsync_duration_seconds(_count|_sum)?|disk_backend_commit_duration_seconds(_count|_sum)?|network_peer_round_trip_time_seconds(_count|_sum)?)$", "tags", "and(component:etcd)", "control plane etcd"},
+		{"allow", "^coredns_(dns_requests_total|dns_responses_total|dns_request_duration_seconds(_count|_sum)?|forward_requests_total|forward_responses_total|cache_hits_total|cache_misses_total|panics_total)$", "tags", "and(component:coredns)", "control plane coredns"},
+		{"allow", "^kubeproxy_sync_proxy_rules_(duration_seconds(_count|_sum)?|last_timestamp_seconds)$", "tags", "and(component:kube-proxy)", "control plane kube-proxy"},
 		{"allow", "^kube_namespace_status_phase$", "tags", "and(or(phase:Active,phase:Terminating))", "namespaces"},
+		{"allow", "^kube_job_status_(active|succeeded|failed)$", "jobs"},
+		{"allow", "^kube_job_(complete|failed)$", "tags", "and(condition:true)", "jobs"},
 		{"allow", "^collect_.*$", "agent collection stats"},
+		{"allow", "^podcache_.*$", "agent pod cache stats"},
 		{"allow", "^events$", "events"},
+		{"allow", "^event_count$", "event counters"},
+		{"allow", "^(up|scrape_duration_seconds|scrape_samples_scraped)$", "tags", "and(source:prometheus)", "prometheus annotated target scrape health"},
 		{"deny", "^.+$", "all other metrics}"},
 	}
 
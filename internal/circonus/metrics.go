@@ -100,6 +100,13 @@ func (c *Check) IncrementCounter(metricName string, tags cgm.Tags) {
 	}
 }
 
+// IncrementCounterByValue to queue for submission
+func (c *Check) IncrementCounterByValue(metricName string, tags cgm.Tags, value uint64) {
+	if c.metrics != nil {
+		c.metrics.IncrementByValueWithTags(metricName, tags, value)
+	}
+}
+
 // SetCounter to queue for submission
 func (c *Check) SetCounter(metricName string, tags cgm.Tags, value uint64) {
 	if c.metrics != nil {
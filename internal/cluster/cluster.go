@@ -12,38 +12,47 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
-	"runtime"
+	"math/rand"
+	"os"
 	"sync"
-	"syscall"
 	"time"
 
-	cgm "github.com/circonus-labs/circonus-gometrics/v3"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/events"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ksm"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/ms"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nodes"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/shard"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workload"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type Cluster struct {
-	tlsConfig  *tls.Config
-	cfg        config.Cluster
-	check      *circonus.Check
-	circCfg    config.Circonus
-	logger     zerolog.Logger
-	interval   time.Duration
-	lastStart  *time.Time
-	collectors []Collector
-	running    bool
-	sync.Mutex
-}
-type Collector interface {
-	ID() string
-	Collect(context.Context, *tls.Config, *time.Time)
+	tlsConfig   *tls.Config
+	cfg         config.Cluster
+	check       *circonus.Check
+	circCfg     config.Circonus
+	logger      zerolog.Logger
+	interval    time.Duration
+	align       bool
+	jitter      time.Duration
+	overrun     string
+	collectors  []registry.Collector
+	schedules   []*schedule
+	streams     []registry.Streaming
+	leader      leaderState
+	shard       *shard.Shard
+	pods        *podcache.Cache // node scoped when collecting the local node
+	clusterPods *podcache.Cache // all pods, for cluster scoped collectors
+	filter      *filter.Filter
+	tags        *tagrules.Rules
+	workloads   *workload.Resolver
+	rates       *rates.Store
 }
 
 func New(cfg config.Cluster, circCfg config.Circonus, parentLog zerolog.Logger) (*Cluster, error) {
@@ -92,6 +101,26 @@ func New(cfg config.Cluster, circCfg config.Circonus, parentLog zerolog.Logger)
 	c.interval = d
 	c.logger.Debug().Str("interval", d.String()).Msg("using interval")
 
+	c.align = c.cfg.AlignInterval
+	if c.cfg.Jitter != "" {
+		d, err := time.ParseDuration(c.cfg.Jitter)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid jitter in cluster configuration")
+		}
+		if d < time.Duration(0) {
+			return nil, errors.Errorf("invalid jitter in cluster configuration (%s)", c.cfg.Jitter)
+		}
+		c.jitter = d
+	}
+	switch c.cfg.OverrunPolicy {
+	case "":
+		c.overrun = overrunSkip
+	case overrunSkip, overrunQueue, overrunCancel:
+		c.overrun = c.cfg.OverrunPolicy
+	default:
+		return nil, errors.Errorf("invalid overrun policy in cluster configuration (%s)", c.cfg.OverrunPolicy)
+	}
+
 	// set check title if it has not been explicitly set by user
 	if circCfg.Check.Title == "" {
 		circCfg.Check.Title = fmt.Sprintf("%s /%s", cfg.Name, release.NAME)
@@ -103,175 +132,362 @@ func New(cfg config.Cluster, circCfg config.Circonus, parentLog zerolog.Logger)
 	}
 	c.check = check
 
-	if c.cfg.EnableNodes {
-		// node metrics, as well as, pod and container metrics (both optional)
-		collector, err := nodes.New(&c.cfg, c.logger, c.check)
+	if c.cfg.EnableSharding {
+		if c.cfg.EnableLeaderElection {
+			return nil, errors.New("use leader election OR sharding, they are mutually exclusive")
+		}
+		sh, err := shard.New(&c.cfg, c.logger)
 		if err != nil {
-			return nil, errors.Wrap(err, "initializing node collector")
+			return nil, errors.Wrap(err, "initializing sharding")
+		}
+		c.shard = sh
+	}
+
+	if c.cfg.LocalNode {
+		if c.cfg.EnableSharding {
+			return nil, errors.New("use local node OR sharding, they are mutually exclusive")
+		}
+		if c.cfg.NodeName == "" {
+			c.cfg.NodeName = os.Getenv(defaults.K8SNodeNameEnv)
 		}
-		c.collectors = append(c.collectors, collector)
+		if c.cfg.NodeName == "" {
+			return nil, errors.Errorf("local node enabled, node name not set (%s env var or node name setting)", defaults.K8SNodeNameEnv)
+		}
+		if !c.cfg.EnableLeaderElection {
+			c.logger.Warn().Msg("local node without leader election, every replica will run the cluster collectors")
+		}
+		c.logger.Debug().Str("node", c.cfg.NodeName).Msg("collecting local node only")
+	}
+
+	if legacyAPIServer(&c.cfg) {
+		c.logger.Warn().Msg("DEPRECATED: enable_metrics_server also enables the api-server collector (tagged deprecated:enable_metrics_server) for now, set enable_api_server to keep collecting api server metrics or list the collectors to run only metrics-server")
 	}
 
-	if c.cfg.EnableKubeStateMetrics {
-		// TODO: does this allow "watching"?
-		collector, err := ksm.New(&c.cfg, c.logger, c.check)
+	configs := collectorConfigs(&c.cfg)
+
+	if c.cfg.IncludePods || usesPodCache(configs) {
+		pc, err := podcache.New(&c.cfg, c.logger, c.check)
 		if err != nil {
-			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
+			return nil, errors.Wrap(err, "initializing pod cache")
+		}
+		c.pods = pc
+		c.clusterPods = pc
+
+		if c.cfg.LocalNode {
+			// the local node cache only holds this node's pods, cluster scoped
+			// collectors (e.g. kube-state-metrics) filter the pods of every node
+			cpc, err := podcache.NewClusterWide(&c.cfg, c.logger, c.check)
+			if err != nil {
+				return nil, errors.Wrap(err, "initializing cluster pod cache")
+			}
+			c.clusterPods = cpc
 		}
-		c.collectors = append(c.collectors, collector)
 	}
 
-	if c.cfg.EnableMetricServer {
-		// TODO: does this allow "watching"?
-		collector, err := ms.New(&c.cfg, c.logger, c.check)
+	if c.cfg.IncludePods {
+		wr, err := workload.New(&c.cfg, c.logger)
 		if err != nil {
-			return nil, errors.Wrap(err, "initializing kube-state-metrics collector")
+			return nil, errors.Wrap(err, "initializing workload resolver")
+		}
+		c.workloads = wr
+	}
+
+	f, err := filter.New(&c.cfg, c.logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing filter")
+	}
+	c.filter = f
+
+	tags, err := tagrules.New(&c.cfg, c.check)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing tag rules")
+	}
+	c.tags = tags
+
+	counters, err := rates.New(&c.cfg, c.check)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing counter rates")
+	}
+	c.rates = counters
+
+	ids := make(map[string]bool)
+	for _, cc := range configs {
+		if ids[cc.Name] {
+			return nil, errors.Errorf("duplicate collector (%s)", cc.Name)
+		}
+		ids[cc.Name] = true
+
+		collector, err := registry.New(cc.Name, registry.Env{
+			Config:      &c.cfg,
+			Options:     cc.Options,
+			Logger:      c.logger,
+			Check:       c.check,
+			Shard:       c.shard,
+			Pods:        c.pods,
+			ClusterPods: c.clusterPods,
+			Filter:      c.filter,
+			Tags:        c.tags,
+			Workloads:   c.workloads,
+			Rates:       c.rates,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch col := collector.(type) {
+		case registry.Periodic:
+			if err := c.addCollector(col, cc.Interval, cc.Offset); err != nil {
+				return nil, err
+			}
+			if w, ok := col.(registry.Watching); ok {
+				c.streams = append(c.streams, watchStream{w})
+			}
+		case registry.Streaming:
+			if cc.Interval != "" || cc.Offset != "" {
+				c.logger.Warn().Str("collector", cc.Name).Msg("streaming collector, ignoring interval and offset")
+			}
+			c.collectors = append(c.collectors, col)
+			c.streams = append(c.streams, col)
 		}
-		c.collectors = append(c.collectors, collector)
 	}
 
 	if len(c.collectors) == 0 {
 		return nil, errors.Errorf("no collectors enabled for cluster %s", c.cfg.Name)
 	}
 
+	if c.clusterPods != c.pods {
+		for _, collector := range c.collectors {
+			if registry.ScopeOf(collector) == registry.ScopeCluster {
+				c.streams = append(c.streams, cacheStream{c.clusterPods})
+				break
+			}
+		}
+	}
+
+	if c.cfg.EnableLeaderElection {
+		if err := c.initLeaderElection(); err != nil {
+			return nil, errors.Wrap(err, "initializing leader election")
+		}
+	}
+
 	return c, nil
 }
 
+// Start collection, when leader election is enabled collection
+// only runs while this replica holds the leader lease (when collecting
+// the local node only the cluster scoped collectors are limited to the leader).
+// The pod cache, workload, and filter informers run on every replica so a
+// standby has warm caches when it acquires the lease.
 func (c *Cluster) Start(ctx context.Context) error {
-	// create a errgroup context based on ctx
-	// if events enabled, create event watcher and add to errgroup
-	// if >0 collectors, start collector goroutine and add to errgroup
-	// errgroup wait
-
-	var eventWatcher *events.Events
-	if c.cfg.EnableEvents {
-		// TODO: events needs to be a separate thing started
-		//       in cluster.Start. It will not return if event
-		//       'watching' works as a stream collector. Which
-		//       means it does not need to be fired every
-		//       cluster.Interval like everything else.
-		ew, err := events.New(&c.cfg, c.logger, c.check)
-		if err != nil {
-			return errors.Wrap(err, "initializing events collector")
-		}
-		eventWatcher = ew
+	if len(c.collectors) == 0 {
+		return errors.New("invalid cluster (zero collectors)")
 	}
 
-	if len(c.collectors) == 0 && eventWatcher == nil {
-		return errors.New("invalid cluster (zero collectors)")
+	if c.cfg.EnableLeaderElection {
+		c.publishLeaderStats()
+		defer leaderStats.Delete(c.cfg.Name)
 	}
 
-	if eventWatcher != nil {
-		go eventWatcher.Start(ctx, c.tlsConfig)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	c.startCaches(ctx, &wg)
+
+	if c.cfg.EnableLeaderElection && c.cfg.LocalNode {
+		// every replica collects its local node, the leader
+		// additionally runs the cluster scoped collectors
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.startLeaderElection(ctx); err != nil {
+				c.logger.Error().Err(err).Msg("leader election")
+			}
+		}()
+		return c.start(ctx)
+	}
+	if c.cfg.EnableLeaderElection {
+		return c.startLeaderElection(ctx)
 	}
+	return c.start(ctx)
+}
+
+// startCaches starts the shared pod cache, workload, and filter
+// informers, they run until ctx is done
+func (c *Cluster) startCaches(ctx context.Context, wg *sync.WaitGroup) {
+	if c.pods != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.pods.Start(ctx)
+		}()
+	}
+	if c.workloads != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.workloads.Start(ctx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.filter.Start(ctx)
+	}()
+}
 
+// start runs the collectors until ctx is done
+func (c *Cluster) start(ctx context.Context) error {
 	if !c.check.ConcurrentSubmissions() {
 		go c.check.Submitter(ctx)
 	}
 
-	c.logger.Info().Str("collection_interval", c.interval.String()).Time("next_collection", time.Now().Add(c.interval)).Msg("client started")
-
-	ticker := time.NewTicker(c.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case <-ticker.C:
-			c.Lock()
-			if c.lastStart != nil {
-				elapsed := time.Since(*c.lastStart)
-				if c.interval.Round(time.Second)-elapsed.Round(time.Second) > 2 {
-					c.Unlock()
-					c.logger.Warn().
-						Str("last_start", c.lastStart.String()).
-						Dur("elapsed", elapsed).
-						Dur("interval", c.interval).
-						Msg("interval not reached")
-					continue
-				}
-			}
-			if c.running {
-				c.Unlock()
-				c.logger.Warn().
-					Str("started", c.lastStart.String()).
-					Str("elapsed", time.Since(*c.lastStart).String()).
-					Msg("collection in progress, not starting another")
-				continue
-			}
+	c.logger.Info().Int("collectors", len(c.collectors)).Msg("client started")
+
+	var wg sync.WaitGroup
+	if c.shard != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.shard.Start(ctx)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.runStats(ctx)
+	}()
+	for _, sc := range c.streams {
+		wg.Add(1)
+		go func(sc registry.Streaming) {
+			defer wg.Done()
+			c.runStream(ctx, sc)
+		}(sc)
+	}
+	for _, s := range c.schedules {
+		wg.Add(1)
+		go func(s *schedule) {
+			defer wg.Done()
+			c.run(ctx, s)
+		}(s)
+	}
+	wg.Wait()
 
-			start := time.Now()
-			c.lastStart = &start
-			c.running = true
-			c.Unlock()
-
-			// reset submit retries metric
-			c.check.SetCounter("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}, 0)
-
-			go func() {
-				var wg sync.WaitGroup
-				wg.Add(len(c.collectors))
-				for _, collector := range c.collectors {
-					if collector.ID() == "events" {
-						continue
-					}
-					go func(collector Collector) {
-						collector.Collect(ctx, c.tlsConfig, &start)
-						wg.Done()
-					}(collector)
-				}
-				wg.Wait()
-
-				cstats := c.check.SubmitStats()
-				c.check.ResetSubmitStats()
-				dur := time.Since(start)
-
-				baseStreamTags := cgm.Tags{
-					cgm.Tag{Category: "cluster", Value: c.cfg.Name},
-					cgm.Tag{Category: "source", Value: release.NAME},
-				}
-				c.check.AddText("collect_agent", baseStreamTags, release.NAME+"_"+release.VERSION)
-				c.check.AddGauge("collect_metrics", baseStreamTags, cstats.Metrics)
-				c.check.AddGauge("collect_ngr", baseStreamTags, uint64(runtime.NumGoroutine()))
-
-				{
-					var streamTags cgm.Tags
-					streamTags = append(streamTags, baseStreamTags...)
-					streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "bytes"})
-					c.check.AddGauge("collect_sent", streamTags, cstats.SentBytes)
-
-					var ms runtime.MemStats
-					runtime.ReadMemStats(&ms)
-					c.check.AddGauge("collect_heap_alloc", streamTags, ms.HeapAlloc)
-					c.check.AddGauge("collect_heap_released", streamTags, ms.HeapReleased)
-					c.check.AddGauge("collect_stack_sys", streamTags, ms.StackSys)
-					c.check.AddGauge("collect_other_sys", streamTags, ms.OtherSys)
-					var mem syscall.Rusage
-					if err := syscall.Getrusage(syscall.RUSAGE_SELF, &mem); err == nil {
-						c.check.AddGauge("collect_max_rss", streamTags, uint64(mem.Maxrss*1024))
-					} else {
-						c.logger.Warn().Err(err).Msg("collecting rss from system")
-					}
-				}
-				{
-					var streamTags cgm.Tags
-					streamTags = append(streamTags, baseStreamTags...)
-					streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "milliseconds"})
-					c.check.AddGauge("collect_duration", streamTags, uint64(dur.Milliseconds()))
-					c.check.AddGauge("collect_interval", streamTags, uint64(c.interval.Milliseconds()))
-				}
-
-				c.check.FlushCGM(ctx, &start)
-
-				c.logger.Info().
-					Interface("metrics_sent", cstats).
-					Str("duration", dur.String()).
-					Msg("collection complete")
-				c.Lock()
-				c.running = false
-				c.Unlock()
-			}()
+	return nil
+}
+
+// addCollector adds a collector with its own collection schedule,
+// a blank interval uses the cluster interval and a blank offset
+// means no delay before the first collection.
+func (c *Cluster) addCollector(collector registry.Periodic, interval, offset string) error {
+	s := &schedule{
+		collector: collector,
+		interval:  c.interval,
+	}
+
+	if interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s interval", collector.ID())
+		}
+		if d <= time.Duration(0) {
+			return errors.Errorf("invalid %s interval (%s)", collector.ID(), interval)
 		}
+		s.interval = d
+	}
+
+	if offset != "" {
+		d, err := time.ParseDuration(offset)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s offset", collector.ID())
+		}
+		if d < time.Duration(0) {
+			return errors.Errorf("invalid %s offset (%s)", collector.ID(), offset)
+		}
+		s.offset = d
+	}
+
+	if c.jitter > time.Duration(0) {
+		s.jitter = time.Duration(rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(int64(c.jitter)))
 	}
+
+	c.logger.Debug().
+		Str("collector", collector.ID()).
+		Str("interval", s.interval.String()).
+		Str("offset", s.offset.String()).
+		Str("jitter", s.jitter.String()).
+		Msg("using schedule")
+
+	c.collectors = append(c.collectors, collector)
+	c.schedules = append(c.schedules, s)
+
+	return nil
+}
+
+// podCacheCollectors take pod state from the shared pod
+// cache, instead of watching pods themselves
+var podCacheCollectors = map[string]bool{
+	"objects":    true,
+	"prometheus": true,
+}
+
+// usesPodCache returns whether any of the collectors uses the shared pod cache
+func usesPodCache(configs []config.CollectorConfig) bool {
+	for _, cc := range configs {
+		if podCacheCollectors[cc.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyAPIServer returns whether the api server collector is enabled
+// by the deprecated meaning of enable_metrics_server
+func legacyAPIServer(cfg *config.Cluster) bool {
+	return len(cfg.Collectors) == 0 && cfg.EnableMetricServer && !cfg.EnableAPIServer
+}
+
+// collectorConfigs returns the collectors configured for a cluster, when
+// none are listed they are derived from the enable_* settings
+func collectorConfigs(cfg *config.Cluster) []config.CollectorConfig {
+	if len(cfg.Collectors) > 0 {
+		return cfg.Collectors
+	}
+
+	var ccs []config.CollectorConfig
+	if cfg.EnableNodes {
+		// node metrics, as well as, pod and container metrics (both optional)
+		ccs = append(ccs, config.CollectorConfig{Name: "nodes", Interval: cfg.NodesInterval, Offset: cfg.NodesOffset})
+	}
+	if cfg.EnableKubeStateMetrics {
+		ccs = append(ccs, config.CollectorConfig{Name: "kube-state-metrics", Interval: cfg.KSMInterval, Offset: cfg.KSMOffset})
+	}
+	if cfg.EnableMetricServer {
+		ccs = append(ccs, config.CollectorConfig{Name: "metrics-server", Interval: cfg.MSInterval, Offset: cfg.MSOffset})
+	}
+	if cfg.EnableAPIServer {
+		ccs = append(ccs, config.CollectorConfig{Name: "api-server", Interval: cfg.APIServerInterval, Offset: cfg.APIServerOffset})
+	} else if legacyAPIServer(cfg) {
+		// deprecated, enable_metrics_server used to collect the api server
+		// /metrics, keep collecting them on the same schedule until
+		// enable_api_server is set, tagged so they can be found
+		ccs = append(ccs, config.CollectorConfig{
+			Name:     "api-server",
+			Interval: cfg.MSInterval,
+			Offset:   cfg.MSOffset,
+			Options:  map[string]string{"deprecated": "enable_metrics_server"},
+		})
+	}
+	if cfg.EnableControlPlane {
+		ccs = append(ccs, config.CollectorConfig{Name: "control-plane", Interval: cfg.ControlPlaneInterval, Offset: cfg.ControlPlaneOffset})
+	}
+	if cfg.EnableObjects {
+		ccs = append(ccs, config.CollectorConfig{Name: "objects", Interval: cfg.ObjectsInterval, Offset: cfg.ObjectsOffset})
+	}
+	if cfg.EnablePrometheus {
+		ccs = append(ccs, config.CollectorConfig{Name: "prometheus", Interval: cfg.PrometheusInterval, Offset: cfg.PrometheusOffset})
+	}
+	if cfg.EnableEvents {
+		ccs = append(ccs, config.CollectorConfig{Name: "events"})
+	}
+
+	return ccs
 }
@@ -5,8 +5,184 @@
 
 package cluster
 
-import "testing"
+import (
+	"context"
+	"crypto/tls"
+	"reflect"
+	"testing"
+	"time"
 
-func Test(t *testing.T) {
-	t.Log("Placeholder...nothing to test currently")
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/rs/zerolog"
+)
+
+type testCollector struct{}
+
+func (tc *testCollector) ID() string {
+	return "test"
+}
+
+func (tc *testCollector) Collect(context.Context, *tls.Config, *time.Time) {}
+
+func TestAddCollector(t *testing.T) {
+	t.Log("Testing addCollector")
+
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	tests := []struct {
+		name       string
+		interval   string
+		offset     string
+		expInt     time.Duration
+		expOff     time.Duration
+		shouldFail bool
+	}{
+		{"defaults", "", "", time.Minute, 0, false},
+		{"custom interval", "30s", "", 30 * time.Second, 0, false},
+		{"custom offset", "5m", "10s", 5 * time.Minute, 10 * time.Second, false},
+		{"invalid interval", "foo", "", 0, 0, true},
+		{"zero interval", "0s", "", 0, 0, true},
+		{"invalid offset", "", "bar", 0, 0, true},
+		{"negative offset", "", "-1s", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			c := &Cluster{interval: time.Minute, logger: zerolog.Nop()}
+			err := c.addCollector(&testCollector{}, test.interval, test.offset)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+			if len(c.schedules) != 1 {
+				t.Fatalf("expected 1 schedule, got %d", len(c.schedules))
+			}
+			if c.schedules[0].interval != test.expInt {
+				t.Fatalf("expected interval %s, got %s", test.expInt, c.schedules[0].interval)
+			}
+			if c.schedules[0].offset != test.expOff {
+				t.Fatalf("expected offset %s, got %s", test.expOff, c.schedules[0].offset)
+			}
+		})
+	}
+}
+
+func TestCollectorConfigs(t *testing.T) {
+	t.Log("Testing collectorConfigs")
+
+	t.Log("derived from enable settings")
+	{
+		cfg := &config.Cluster{
+			EnableNodes:        true,
+			NodesInterval:      "30s",
+			EnableMetricServer: true,
+			EnableAPIServer:    true,
+			EnableControlPlane: true,
+			EnableObjects:      true,
+			ObjectsOffset:      "10s",
+			EnablePrometheus:   true,
+			EnableEvents:       true,
+		}
+		expect := []config.CollectorConfig{
+			{Name: "nodes", Interval: "30s"},
+			{Name: "metrics-server"},
+			{Name: "api-server"},
+			{Name: "control-plane"},
+			{Name: "objects", Offset: "10s"},
+			{Name: "prometheus"},
+			{Name: "events"},
+		}
+		ccs := collectorConfigs(cfg)
+		if !reflect.DeepEqual(ccs, expect) {
+			t.Fatalf("expected %v, got %v", expect, ccs)
+		}
+	}
+
+	t.Log("deprecated metrics server setting keeps the api server collector")
+	{
+		cfg := &config.Cluster{
+			EnableMetricServer: true,
+			MSInterval:         "5m",
+		}
+		expect := []config.CollectorConfig{
+			{Name: "metrics-server", Interval: "5m"},
+			{Name: "api-server", Interval: "5m", Options: map[string]string{"deprecated": "enable_metrics_server"}},
+		}
+		ccs := collectorConfigs(cfg)
+		if !reflect.DeepEqual(ccs, expect) {
+			t.Fatalf("expected %v, got %v", expect, ccs)
+		}
+	}
+
+	t.Log("explicit list")
+	{
+		cfg := &config.Cluster{
+			EnableNodes: true,
+			Collectors: []config.CollectorConfig{
+				{Name: "custom", Options: map[string]string{"foo": "bar"}},
+			},
+		}
+		ccs := collectorConfigs(cfg)
+		if !reflect.DeepEqual(ccs, cfg.Collectors) {
+			t.Fatalf("expected %v, got %v", cfg.Collectors, ccs)
+		}
+	}
+}
+
+func TestScheduleStarts(t *testing.T) {
+	t.Log("Testing firstStart/nextStart")
+
+	now := time.Date(2020, 2, 1, 10, 0, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		s       *schedule
+		align   bool
+		expect  time.Time
+		expNext time.Time
+	}{
+		{"unaligned", &schedule{interval: time.Minute}, false,
+			time.Date(2020, 2, 1, 10, 1, 5, 0, time.UTC),
+			time.Date(2020, 2, 1, 10, 2, 5, 0, time.UTC)},
+		{"unaligned offset", &schedule{interval: time.Minute, offset: 10 * time.Second}, false,
+			time.Date(2020, 2, 1, 10, 1, 15, 0, time.UTC),
+			time.Date(2020, 2, 1, 10, 2, 15, 0, time.UTC)},
+		{"aligned", &schedule{interval: 30 * time.Second}, true,
+			time.Date(2020, 2, 1, 10, 0, 30, 0, time.UTC),
+			time.Date(2020, 2, 1, 10, 1, 0, 0, time.UTC)},
+		{"aligned offset", &schedule{interval: time.Minute, offset: 10 * time.Second}, true,
+			time.Date(2020, 2, 1, 10, 0, 10, 0, time.UTC),
+			time.Date(2020, 2, 1, 10, 1, 10, 0, time.UTC)},
+		{"aligned offset+jitter", &schedule{interval: time.Minute, offset: 2 * time.Second, jitter: time.Second}, true,
+			time.Date(2020, 2, 1, 10, 1, 3, 0, time.UTC),
+			time.Date(2020, 2, 1, 10, 2, 3, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			first := test.s.firstStart(now, test.align)
+			if !first.Equal(test.expect) {
+				t.Fatalf("expected first %s, got %s", test.expect, first)
+			}
+			next := test.s.nextStart(first, first)
+			if !next.Equal(test.expNext) {
+				t.Fatalf("expected next %s, got %s", test.expNext, next)
+			}
+		})
+	}
+
+	t.Log("missed collections are not made up")
+	s := schedule{interval: time.Minute}
+	prev := time.Date(2020, 2, 1, 10, 0, 0, 0, time.UTC)
+	next := s.nextStart(prev, prev.Add(3*time.Minute+time.Second))
+	if expect := prev.Add(4 * time.Minute); !next.Equal(expect) {
+		t.Fatalf("expected %s, got %s", expect, next)
+	}
 }
@@ -0,0 +1,18 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+// collectors register themselves with the registry
+import (
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/apiserver"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/controlplane"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/events"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/ksm"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/ms"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/nodes"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/objects"
+	_ "github.com/circonus-labs/circonus-kubernetes-agent/internal/prom"
+)
@@ -0,0 +1,185 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+	"expvar"
+	"os"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// leaderStats exposes the leader election state of each cluster in /stats
+var leaderStats = expvar.NewMap("leader_election")
+
+type leaderInfo struct {
+	Identity      string    `json:"identity"`
+	Namespace     string    `json:"namespace"`
+	Lease         string    `json:"lease"`
+	Leader        bool      `json:"leader"`
+	CurrentLeader string    `json:"current_leader"`
+	Transition    time.Time `json:"last_transition"`
+}
+
+type leaderState struct {
+	info leaderInfo
+	sync.Mutex
+}
+
+// initLeaderElection determines the identity and lease to use for leader election
+func (c *Cluster) initLeaderElection() error {
+	identity, err := os.Hostname()
+	if err != nil {
+		return errors.Wrap(err, "leader election identity")
+	}
+
+	ns := c.cfg.LeaderElectionNS
+	if ns == "" {
+		ns, err = k8s.AgentNamespace()
+		if err != nil {
+			return errors.Wrap(err, "leader election namespace")
+		}
+	}
+
+	name := c.cfg.LeaderElectionName
+	if name == "" {
+		name = defaults.K8SLeaderElectionName
+	}
+
+	c.leader.Lock()
+	c.leader.info = leaderInfo{
+		Identity:  identity,
+		Namespace: ns,
+		Lease:     name,
+	}
+	c.leader.Unlock()
+
+	c.logger.Debug().
+		Str("identity", identity).
+		Str("lease", ns+"/"+name).
+		Msg("using leader election")
+
+	return nil
+}
+
+// publishLeaderStats adds the cluster's leader election state to /stats,
+// it is removed when the cluster stops (e.g. removed by a reload)
+func (c *Cluster) publishLeaderStats() {
+	leaderStats.Set(c.cfg.Name, expvar.Func(func() interface{} {
+		return c.leaderInfo()
+	}))
+}
+
+// startLeaderElection participates in leader election until ctx is done,
+// collection is started when leadership is acquired and stopped when lost
+func (c *Cluster) startLeaderElection(ctx context.Context) error {
+	clientset, err := k8s.NewClientset(&c.cfg)
+	if err != nil {
+		return errors.Wrap(err, "leader election")
+	}
+
+	info := c.leaderInfo()
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      info.Lease,
+			Namespace: info.Namespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: info.Identity,
+		},
+	}
+
+	for {
+		elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   defaults.K8SLeaseDuration,
+			RenewDeadline:   defaults.K8SLeaseRenewDeadline,
+			RetryPeriod:     defaults.K8SLeaseRetryPeriod,
+			ReleaseOnCancel: true,
+			Name:            c.cfg.Name,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leaderCtx context.Context) {
+					c.setLeader(true)
+					if c.cfg.LocalNode {
+						c.logger.Info().Str("identity", info.Identity).Msg("acquired leadership, running cluster collectors")
+						return
+					}
+					c.logger.Info().Str("identity", info.Identity).Msg("acquired leadership, starting collection")
+					if err := c.start(leaderCtx); err != nil {
+						c.logger.Error().Err(err).Msg("starting collection")
+					}
+				},
+				OnStoppedLeading: func() {
+					c.setLeader(false)
+					c.logger.Info().Str("identity", info.Identity).Msg("stopped leading, collection stopped")
+					if ctx.Err() == nil {
+						ts := time.Now()
+						c.check.AddGauge("collect_leader", cgm.Tags{
+							cgm.Tag{Category: "cluster", Value: c.cfg.Name},
+							cgm.Tag{Category: "source", Value: release.NAME},
+						}, uint64(0))
+						c.check.FlushCGM(ctx, &ts)
+					}
+				},
+				OnNewLeader: func(identity string) {
+					c.setCurrentLeader(identity)
+					if identity != info.Identity {
+						c.logger.Info().Str("leader", identity).Msg("standby, another replica is leading")
+					}
+				},
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "leader election")
+		}
+
+		elector.Run(ctx) // blocks until ctx is done or leadership is lost
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			c.logger.Warn().Msg("leadership lost, rejoining election as standby")
+		}
+	}
+}
+
+// isLeader returns whether this replica is currently the leader
+func (c *Cluster) isLeader() bool {
+	c.leader.Lock()
+	defer c.leader.Unlock()
+	return c.leader.info.Leader
+}
+
+func (c *Cluster) setLeader(leader bool) {
+	c.leader.Lock()
+	defer c.leader.Unlock()
+	c.leader.info.Leader = leader
+	c.leader.info.Transition = time.Now()
+}
+
+func (c *Cluster) setCurrentLeader(identity string) {
+	c.leader.Lock()
+	defer c.leader.Unlock()
+	c.leader.info.CurrentLeader = identity
+}
+
+func (c *Cluster) leaderInfo() leaderInfo {
+	c.leader.Lock()
+	defer c.leader.Unlock()
+	return c.leader.info
+}
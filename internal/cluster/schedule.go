@@ -0,0 +1,273 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+)
+
+// overrun policies, what to do when a collection is due while the
+// previous collection for the same collector is still running
+const (
+	overrunSkip   = "skip"   // skip the new collection
+	overrunQueue  = "queue"  // start one new collection when the running one finishes
+	overrunCancel = "cancel" // cancel collections running longer than the interval
+)
+
+// schedule tracks the collection cadence of an individual collector
+type schedule struct {
+	collector registry.Periodic
+	interval  time.Duration
+	offset    time.Duration
+	jitter    time.Duration
+	lastStart *time.Time
+	running   bool
+	queued    bool
+	sync.Mutex
+}
+
+// run drives a single collector at its own interval until ctx is done
+func (c *Cluster) run(ctx context.Context, s *schedule) {
+	next := s.firstStart(time.Now(), c.align)
+
+	c.logger.Info().
+		Str("collector", s.collector.ID()).
+		Str("collection_interval", s.interval.String()).
+		Time("next_collection", next).
+		Msg("collector started")
+
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			c.collect(ctx, s)
+			next = s.nextStart(next, time.Now())
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// firstStart returns the time of the first collection, either one
+// interval from now or the next wall clock boundary of the interval
+// (e.g. :00, :30 for 30s), both shifted by the offset and jitter
+func (s *schedule) firstStart(now time.Time, align bool) time.Time {
+	delay := s.offset + s.jitter
+	if !align {
+		return now.Add(delay).Add(s.interval)
+	}
+	return now.Add(-delay).Truncate(s.interval).Add(s.interval).Add(delay)
+}
+
+// nextStart returns the time of the collection following prev,
+// missed collections (e.g. process was suspended) are not made up
+func (s *schedule) nextStart(prev, now time.Time) time.Time {
+	next := prev.Add(s.interval)
+	for !next.After(now) {
+		next = next.Add(s.interval)
+	}
+	return next
+}
+
+// collect starts a collection for a single collector, when the
+// previous collection is still running the overrun policy applies
+func (c *Cluster) collect(ctx context.Context, s *schedule) {
+	if !c.shouldRun(s.collector) {
+		c.logger.Debug().Str("collector", s.collector.ID()).Msg("not primary, skipping cluster collector")
+		return
+	}
+
+	s.Lock()
+	if s.running {
+		if c.overrun == overrunQueue && !s.queued {
+			s.queued = true
+			s.Unlock()
+			c.logger.Warn().
+				Str("collector", s.collector.ID()).
+				Str("started", s.lastStart.String()).
+				Str("elapsed", time.Since(*s.lastStart).String()).
+				Msg("collection in progress, queued next collection")
+			return
+		}
+		s.Unlock()
+		c.logger.Warn().
+			Str("collector", s.collector.ID()).
+			Str("started", s.lastStart.String()).
+			Str("elapsed", time.Since(*s.lastStart).String()).
+			Str("overrun_policy", c.overrun).
+			Msg("collection in progress, skipping")
+		c.check.IncrementCounter("collect_cycles_skipped", c.collectorTags(s))
+		return
+	}
+
+	start := time.Now()
+	s.lastStart = &start
+	s.running = true
+	s.Unlock()
+
+	go func() {
+		for {
+			c.cycle(ctx, s, start)
+
+			s.Lock()
+			if !s.queued || ctx.Err() != nil {
+				s.running = false
+				s.queued = false
+				s.Unlock()
+				return
+			}
+			s.queued = false
+			start = time.Now()
+			s.lastStart = &start
+			s.Unlock()
+
+			c.logger.Info().Str("collector", s.collector.ID()).Msg("starting queued collection")
+		}
+	}()
+}
+
+// cycle runs a single collection and emits the collector's duration,
+// agent wide metrics are emitted by runStats
+func (c *Cluster) cycle(ctx context.Context, s *schedule, start time.Time) {
+	cycleCtx := ctx
+	if c.overrun == overrunCancel {
+		var cancel context.CancelFunc
+		cycleCtx, cancel = context.WithTimeout(ctx, s.interval)
+		defer cancel()
+	}
+
+	s.collector.Collect(cycleCtx, c.tlsConfig, &start)
+
+	if cycleCtx.Err() == context.DeadlineExceeded {
+		c.logger.Warn().
+			Str("collector", s.collector.ID()).
+			Str("elapsed", time.Since(start).String()).
+			Msg("collection exceeded interval, cancelled")
+		c.check.IncrementCounter("collect_cycles_cancelled", c.collectorTags(s))
+	}
+
+	dur := time.Since(start)
+	{
+		streamTags := c.collectorTags(s)
+		streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "milliseconds"})
+		c.check.AddGauge("collect_duration", streamTags, uint64(dur.Milliseconds()))
+		c.check.AddGauge("collect_interval", streamTags, uint64(s.interval.Milliseconds()))
+	}
+
+	c.logger.Info().
+		Str("collector", s.collector.ID()).
+		Str("duration", dur.String()).
+		Msg("collection complete")
+}
+
+// runStats emits and flushes the agent wide metrics every cluster
+// interval until ctx is done, the submit stats cover the submissions
+// of all collectors since the previous flush
+func (c *Cluster) runStats(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ts := <-ticker.C:
+			c.agentMetrics(ctx, ts)
+		}
+	}
+}
+
+// agentMetrics emits the agent wide metrics and flushes
+// them, along with the collector metrics, to the check
+func (c *Cluster) agentMetrics(ctx context.Context, ts time.Time) {
+	baseStreamTags := cgm.Tags{
+		cgm.Tag{Category: "cluster", Value: c.cfg.Name},
+		cgm.Tag{Category: "source", Value: release.NAME},
+	}
+
+	cstats := c.check.SubmitStats()
+	c.check.ResetSubmitStats()
+
+	c.check.AddText("collect_agent", baseStreamTags, release.NAME+"_"+release.VERSION)
+	c.check.AddGauge("collect_metrics", baseStreamTags, cstats.Metrics)
+	c.check.AddGauge("collect_ngr", baseStreamTags, uint64(runtime.NumGoroutine()))
+	if c.cfg.EnableLeaderElection {
+		leader := uint64(0)
+		if c.isLeader() {
+			leader = 1
+		}
+		c.check.AddGauge("collect_leader", baseStreamTags, leader)
+	}
+	if c.pods != nil {
+		if c.clusterPods != c.pods {
+			c.pods.AddMetrics(append(cgm.Tags{cgm.Tag{Category: "scope", Value: "node"}}, baseStreamTags...))
+			c.clusterPods.AddMetrics(append(cgm.Tags{cgm.Tag{Category: "scope", Value: "cluster"}}, baseStreamTags...))
+		} else {
+			c.pods.AddMetrics(baseStreamTags)
+		}
+	}
+	c.tags.AddMetrics(baseStreamTags)
+	c.rates.AddMetrics(baseStreamTags)
+	if c.shard != nil {
+		info := c.shard.Info()
+		streamTags := append(cgm.Tags{cgm.Tag{Category: "shard", Value: info.Identity}}, baseStreamTags...)
+		primary := uint64(0)
+		if info.Primary {
+			primary = 1
+		}
+		c.check.AddGauge("collect_shard_members", streamTags, uint64(len(info.Members)))
+		c.check.AddGauge("collect_shard_primary", streamTags, primary)
+	}
+
+	{
+		var streamTags cgm.Tags
+		streamTags = append(streamTags, baseStreamTags...)
+		streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "bytes"})
+		c.check.AddGauge("collect_sent", streamTags, cstats.SentBytes)
+
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		c.check.AddGauge("collect_heap_alloc", streamTags, ms.HeapAlloc)
+		c.check.AddGauge("collect_heap_released", streamTags, ms.HeapReleased)
+		c.check.AddGauge("collect_stack_sys", streamTags, ms.StackSys)
+		c.check.AddGauge("collect_other_sys", streamTags, ms.OtherSys)
+		var mem syscall.Rusage
+		if err := syscall.Getrusage(syscall.RUSAGE_SELF, &mem); err == nil {
+			c.check.AddGauge("collect_max_rss", streamTags, uint64(mem.Maxrss*1024))
+		} else {
+			c.logger.Warn().Err(err).Msg("collecting rss from system")
+		}
+	}
+
+	c.check.FlushCGM(ctx, &ts)
+
+	// reset submit retries metric
+	c.check.SetCounter("collect_submit_retries", cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}, 0)
+
+	c.logger.Info().
+		Interface("metrics_sent", cstats).
+		Msg("agent metrics flushed")
+}
+
+// collectorTags returns the stream tags for agent metrics of a collector
+func (c *Cluster) collectorTags(s *schedule) cgm.Tags {
+	return cgm.Tags{
+		cgm.Tag{Category: "cluster", Value: c.cfg.Name},
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "collector", Value: s.collector.ID()},
+	}
+}
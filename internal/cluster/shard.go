@@ -0,0 +1,142 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+)
+
+// gated returns whether cluster scoped collectors run on only one replica,
+// the primary shard when sharding or the leader when each replica
+// collects its local node (daemonset)
+func (c *Cluster) gated() bool {
+	return c.shard != nil || (c.cfg.LocalNode && c.cfg.EnableLeaderElection)
+}
+
+// primary returns whether this replica runs the cluster scoped collectors
+func (c *Cluster) primary() bool {
+	switch {
+	case c.shard != nil:
+		return c.shard.Primary()
+	case c.cfg.LocalNode && c.cfg.EnableLeaderElection:
+		return c.isLeader()
+	default:
+		return true
+	}
+}
+
+// shouldRun returns whether a collector runs on this replica, when sharding
+// or collecting the local node cluster scoped collectors only run on the primary
+func (c *Cluster) shouldRun(collector registry.Collector) bool {
+	if !c.gated() || registry.ScopeOf(collector) == registry.ScopeNode {
+		return true
+	}
+	return c.primary()
+}
+
+// watchStream runs the watch of a watching periodic collector
+// as a streaming collector, with the scope of the collector
+type watchStream struct {
+	registry.Watching
+}
+
+func (w watchStream) Start(ctx context.Context, _ *tls.Config) {
+	w.Watch(ctx)
+}
+
+func (w watchStream) Scope() registry.Scope {
+	return registry.ScopeOf(w.Watching)
+}
+
+// cacheStream runs the cluster wide pod cache as a cluster scoped
+// streaming collector when collecting the local node, so only the
+// replica running the cluster scoped collectors caches every pod
+type cacheStream struct {
+	pods *podcache.Cache
+}
+
+func (cs cacheStream) ID() string {
+	return "podcache"
+}
+
+func (cs cacheStream) Start(ctx context.Context, _ *tls.Config) {
+	cs.pods.Start(ctx)
+}
+
+// runStream runs a streaming collector until ctx is done, when gated
+// a cluster scoped streaming collector is started when this replica
+// becomes the primary and stopped when it no longer is
+func (c *Cluster) runStream(ctx context.Context, sc registry.Streaming) {
+	if !c.gated() || registry.ScopeOf(sc) == registry.ScopeNode {
+		c.logger.Info().Str("collector", sc.ID()).Msg("collector started")
+		sc.Start(ctx, c.tlsConfig)
+		return
+	}
+
+	g := &gatedStream{Streaming: sc, c: c}
+	defer g.stop()
+
+	ticker := time.NewTicker(defaults.K8SShardRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		primary := c.primary()
+		switch {
+		case primary && !g.running():
+			g.start(ctx)
+		case !primary && g.running():
+			g.stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// gatedStream is a cluster scoped streaming collector started and
+// stopped as this replica gains and loses the primary role
+type gatedStream struct {
+	registry.Streaming
+	c      *Cluster
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (g *gatedStream) running() bool {
+	return g.cancel != nil
+}
+
+// start runs the collector until stop is called or ctx is done
+func (g *gatedStream) start(ctx context.Context) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	g.cancel = cancel
+	g.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		g.Start(streamCtx, g.c.tlsConfig)
+	}(g.done)
+	g.c.logger.Info().Str("collector", g.ID()).Msg("primary, collector started")
+}
+
+// stop cancels the collector and waits for it to return
+func (g *gatedStream) stop() {
+	if g.cancel == nil {
+		return
+	}
+	g.cancel()
+	<-g.done
+	g.cancel = nil
+	g.c.logger.Info().Str("collector", g.ID()).Msg("collector stopped")
+}
@@ -11,6 +11,7 @@ import (
 	"expvar"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/keys"
 	toml "github.com/pelletier/go-toml"
@@ -21,41 +22,132 @@ import (
 
 // Config defines the running configuration options
 type Config struct {
-	Circonus   Circonus  `json:"circonus" toml:"circonus" yaml:"circonus"`       // circonus configuration options
-	Kubernetes Cluster   `json:"kubernetes" toml:"kubernetes" yaml:"kubernetes"` // single cluster (use kubernetes OR clusters, not both)
-	Clusters   []Cluster `json:"clusters" toml:"clusters" yaml:"clusters"`       // multiple clusters (use kubernetes OR clusters, not both)
-	Debug      bool      `json:"debug" toml:"debug" yaml:"debug"`                // global debugging
-	Log        Log       `json:"log" toml:"log" yaml:"log"`                      // logging options
+	Circonus    Circonus  `json:"circonus" toml:"circonus" yaml:"circonus"`                                         // circonus configuration options
+	Kubernetes  Cluster   `json:"kubernetes" toml:"kubernetes" yaml:"kubernetes"`                                   // single cluster (use kubernetes OR clusters, not both)
+	Clusters    []Cluster `json:"clusters" toml:"clusters" yaml:"clusters"`                                         // multiple clusters (use kubernetes OR clusters, not both)
+	Debug       bool      `json:"debug" toml:"debug" yaml:"debug"`                                                  // global debugging
+	Log         Log       `json:"log" toml:"log" yaml:"log"`                                                        // logging options
+	WatchConfig bool      `mapstructure:"watch_config" json:"watch_config" toml:"watch_config" yaml:"watch_config"` // reload when config file changes
 }
 
 // Cluster defines the kubernetes cluster configuration options
 type Cluster struct {
-	BearerToken            string `mapstructure:"bearer_token" json:"bearer_token" toml:"bearer_token" yaml:"bearer_token"`
-	BearerTokenFile        string `mapstructure:"bearer_token_file" json:"bearer_token_file" toml:"bearer_token_file" yaml:"bearer_token_file"`
-	EnableEvents           bool   `mapstructure:"enable_events" json:"enable_events" toml:"enable_events" yaml:"enable_events"`
-	EnableKubeStateMetrics bool   `mapstructure:"enable_kube_state_metrics" json:"enable_kube_state_metrics" toml:"enable_kube_state_metrics" yaml:"enable_kube_state_metrics"`
-	EnableMetricServer     bool   `mapstructure:"enable_metrics_server" json:"enable_metrics_server" toml:"enable_metrics_server" yaml:"enable_metrics_server"`
-	EnableNodes            bool   `mapstructure:"enable_nodes" json:"enable_nodes" toml:"enable_nodes" yaml:"enable_nodes"`
-	NodeSelector           string `mapstructure:"node_selector" json:"node_selector" toml:"node_selector" yaml:"node_selector"`
-	EnableNodeStats        bool   `mapstructure:"enable_node_stats" json:"enable_node_stats" toml:"enable_node_stats" yaml:"enable_node_stats"`
-	EnableNodeMetrics      bool   `mapstructure:"enable_node_metrics" json:"enable_node_metrics" toml:"enable_node_metrics" yaml:"enable_node_metrics"`
-	EnableCadvisorMetrics  bool   `mapstructure:"enable_cadvisor_metrics" json:"enable_cadvisor_metrics" toml:"enable_cadvisor_metrics" yaml:"enable_cadvisor_metrics"`
-	IncludeContainers      bool   `mapstructure:"include_container_metrics" json:"include_container_metrics" toml:"include_container_metrics" yaml:"include_container_metrics"`
-	IncludePods            bool   `mapstructure:"include_pod_metrics" json:"include_pod_metrics" toml:"include_pod_metrics" yaml:"include_pod_metrics"`
-	PodLabelKey            string `mapstructure:"pod_label_key" json:"pod_label_key" toml:"pod_label" yaml:"pod_label_key"`
-	PodLabelVal            string `mapstructure:"pod_label_val" json:"pod_label_val" toml:"pod_label" yaml:"pod_label_val"`
-	Name                   string `json:"name" toml:"name" yaml:"name"`
-	Interval               string `json:"interval" toml:"interval" yaml:"interval"`
-	NodePoolSize           uint   `mapstructure:"node_pool_size" json:"node_pool_size" toml:"node_pool_size" yaml:"node_pool_size"`
-	URL                    string `mapstructure:"api_url" json:"api_url" toml:"api_url" yaml:"api_url"`
-	CAFile                 string `mapstructure:"api_ca_file" json:"api_ca_file" toml:"api_ca_file" yaml:"api_ca_file"`
-	APITimelimit           string `mapstructure:"api_timelimit" json:"api_timelimit" toml:"api_timelimit" yaml:"api_timelimit"`
+	BearerToken                 string                  `mapstructure:"bearer_token" json:"bearer_token" toml:"bearer_token" yaml:"bearer_token"`
+	BearerTokenFile             string                  `mapstructure:"bearer_token_file" json:"bearer_token_file" toml:"bearer_token_file" yaml:"bearer_token_file"`
+	EnableEvents                bool                    `mapstructure:"enable_events" json:"enable_events" toml:"enable_events" yaml:"enable_events"`
+	EventsCheckpointFile        string                  `mapstructure:"events_checkpoint_file" json:"events_checkpoint_file" toml:"events_checkpoint_file" yaml:"events_checkpoint_file"`                     // blank=not persisted to a file
+	EventsCheckpointConfigMap   string                  `mapstructure:"events_checkpoint_configmap" json:"events_checkpoint_configmap" toml:"events_checkpoint_configmap" yaml:"events_checkpoint_configmap"` // configmap in the agent namespace, blank=not persisted to a configmap
+	EventsInclude               string                  `mapstructure:"events_include" json:"events_include" toml:"events_include" yaml:"events_include"`                                                     // comma separated field=pattern (namespace, type, reason, kind), blank=all events
+	EventsExclude               string                  `mapstructure:"events_exclude" json:"events_exclude" toml:"events_exclude" yaml:"events_exclude"`                                                     // comma separated field=pattern (namespace, type, reason, kind)
+	EventsAnnotations           bool                    `mapstructure:"events_annotations" json:"events_annotations" toml:"events_annotations" yaml:"events_annotations"`
+	EventsAnnotationMatch       string                  `mapstructure:"events_annotation_match" json:"events_annotation_match" toml:"events_annotation_match" yaml:"events_annotation_match"`                         // comma separated field=pattern, events matching any are annotated, blank=all events
+	EventsAnnotationTitle       string                  `mapstructure:"events_annotation_title" json:"events_annotation_title" toml:"events_annotation_title" yaml:"events_annotation_title"`                         // text/template
+	EventsAnnotationCategory    string                  `mapstructure:"events_annotation_category" json:"events_annotation_category" toml:"events_annotation_category" yaml:"events_annotation_category"`             // text/template
+	EventsAnnotationDescription string                  `mapstructure:"events_annotation_description" json:"events_annotation_description" toml:"events_annotation_description" yaml:"events_annotation_description"` // text/template
+	EventsAnnotationWindow      string                  `mapstructure:"events_annotation_window" json:"events_annotation_window" toml:"events_annotation_window" yaml:"events_annotation_window"`                     // events with the same title and category within the window are one annotation
+	EventsAnnotationRateLimit   uint                    `mapstructure:"events_annotation_rate_limit" json:"events_annotation_rate_limit" toml:"events_annotation_rate_limit" yaml:"events_annotation_rate_limit"`     // annotations per minute, 0=unlimited
+	EnableKubeStateMetrics      bool                    `mapstructure:"enable_kube_state_metrics" json:"enable_kube_state_metrics" toml:"enable_kube_state_metrics" yaml:"enable_kube_state_metrics"`
+	EnableMetricServer          bool                    `mapstructure:"enable_metrics_server" json:"enable_metrics_server" toml:"enable_metrics_server" yaml:"enable_metrics_server"`
+	EnableAPIServer             bool                    `mapstructure:"enable_api_server" json:"enable_api_server" toml:"enable_api_server" yaml:"enable_api_server"`
+	EnableControlPlane          bool                    `mapstructure:"enable_control_plane" json:"enable_control_plane" toml:"enable_control_plane" yaml:"enable_control_plane"`
+	EnableObjects               bool                    `mapstructure:"enable_objects" json:"enable_objects" toml:"enable_objects" yaml:"enable_objects"`
+	EnablePrometheus            bool                    `mapstructure:"enable_prometheus" json:"enable_prometheus" toml:"enable_prometheus" yaml:"enable_prometheus"`
+	PrometheusAnnotationPrefix  string                  `mapstructure:"prometheus_annotation_prefix" json:"prometheus_annotation_prefix" toml:"prometheus_annotation_prefix" yaml:"prometheus_annotation_prefix"` // <prefix>/scrape, <prefix>/port, <prefix>/path, <prefix>/scheme
+	PrometheusScrapeTimeout     string                  `mapstructure:"prometheus_scrape_timeout" json:"prometheus_scrape_timeout" toml:"prometheus_scrape_timeout" yaml:"prometheus_scrape_timeout"`             // per target
+	PrometheusConcurrency       uint                    `mapstructure:"prometheus_concurrency" json:"prometheus_concurrency" toml:"prometheus_concurrency" yaml:"prometheus_concurrency"`                         // targets scraped at once
+	EnableNodes                 bool                    `mapstructure:"enable_nodes" json:"enable_nodes" toml:"enable_nodes" yaml:"enable_nodes"`
+	NodeSelector                string                  `mapstructure:"node_selector" json:"node_selector" toml:"node_selector" yaml:"node_selector"`
+	EnableNodeStats             bool                    `mapstructure:"enable_node_stats" json:"enable_node_stats" toml:"enable_node_stats" yaml:"enable_node_stats"`
+	EnableNodeMetrics           bool                    `mapstructure:"enable_node_metrics" json:"enable_node_metrics" toml:"enable_node_metrics" yaml:"enable_node_metrics"`
+	EnableCadvisorMetrics       bool                    `mapstructure:"enable_cadvisor_metrics" json:"enable_cadvisor_metrics" toml:"enable_cadvisor_metrics" yaml:"enable_cadvisor_metrics"`
+	EnableResourceMetrics       bool                    `mapstructure:"enable_resource_metrics" json:"enable_resource_metrics" toml:"enable_resource_metrics" yaml:"enable_resource_metrics"`
+	EnableProbeMetrics          bool                    `mapstructure:"enable_probe_metrics" json:"enable_probe_metrics" toml:"enable_probe_metrics" yaml:"enable_probe_metrics"`
+	IncludeContainers           bool                    `mapstructure:"include_container_metrics" json:"include_container_metrics" toml:"include_container_metrics" yaml:"include_container_metrics"`
+	IncludePods                 bool                    `mapstructure:"include_pod_metrics" json:"include_pod_metrics" toml:"include_pod_metrics" yaml:"include_pod_metrics"`
+	PodLabelKey                 string                  `mapstructure:"pod_label_key" json:"pod_label_key" toml:"pod_label" yaml:"pod_label_key"`
+	PodLabelVal                 string                  `mapstructure:"pod_label_val" json:"pod_label_val" toml:"pod_label" yaml:"pod_label_val"`
+	PodSelector                 string                  `mapstructure:"pod_selector" json:"pod_selector" toml:"pod_selector" yaml:"pod_selector"`                         // kubernetes label selector, blank=all pods
+	NamespaceInclude            string                  `mapstructure:"namespace_include" json:"namespace_include" toml:"namespace_include" yaml:"namespace_include"`     // comma separated, blank=all namespaces
+	NamespaceExclude            string                  `mapstructure:"namespace_exclude" json:"namespace_exclude" toml:"namespace_exclude" yaml:"namespace_exclude"`     // comma separated
+	CollectAnnotation           string                  `mapstructure:"collect_annotation" json:"collect_annotation" toml:"collect_annotation" yaml:"collect_annotation"` // pods/namespaces annotated "false" are not collected, blank=disabled
+	TagAllow                    string                  `mapstructure:"tag_allow" json:"tag_allow" toml:"tag_allow" yaml:"tag_allow"`                                     // comma separated label key patterns to turn into tags, blank=all
+	TagDeny                     string                  `mapstructure:"tag_deny" json:"tag_deny" toml:"tag_deny" yaml:"tag_deny"`                                         // comma separated label key patterns to drop
+	TagRename                   string                  `mapstructure:"tag_rename" json:"tag_rename" toml:"tag_rename" yaml:"tag_rename"`                                 // comma separated from=to label key renames
+	CounterRates                string                  `mapstructure:"counter_rates" json:"counter_rates" toml:"counter_rates" yaml:"counter_rates"`                     // comma separated counter metric name patterns to emit per second rates for
+	CounterDeltas               string                  `mapstructure:"counter_deltas" json:"counter_deltas" toml:"counter_deltas" yaml:"counter_deltas"`                 // comma separated counter metric name patterns to emit deltas for
+	Name                        string                  `json:"name" toml:"name" yaml:"name"`
+	Interval                    string                  `json:"interval" toml:"interval" yaml:"interval"`
+	NodesInterval               string                  `mapstructure:"nodes_interval" json:"nodes_interval" toml:"nodes_interval" yaml:"nodes_interval"`                                                                             // blank=interval
+	NodesOffset                 string                  `mapstructure:"nodes_offset" json:"nodes_offset" toml:"nodes_offset" yaml:"nodes_offset"`                                                                                     // blank=none
+	KSMInterval                 string                  `mapstructure:"kube_state_metrics_interval" json:"kube_state_metrics_interval" toml:"kube_state_metrics_interval" yaml:"kube_state_metrics_interval"`                         // blank=interval
+	KSMOffset                   string                  `mapstructure:"kube_state_metrics_offset" json:"kube_state_metrics_offset" toml:"kube_state_metrics_offset" yaml:"kube_state_metrics_offset"`                                 // blank=none
+	KSMNamespace                string                  `mapstructure:"kube_state_metrics_namespace" json:"kube_state_metrics_namespace" toml:"kube_state_metrics_namespace" yaml:"kube_state_metrics_namespace"`                     // blank=all namespaces
+	KSMService                  string                  `mapstructure:"kube_state_metrics_service" json:"kube_state_metrics_service" toml:"kube_state_metrics_service" yaml:"kube_state_metrics_service"`                             // service name, blank=any (use selector)
+	KSMSelector                 string                  `mapstructure:"kube_state_metrics_selector" json:"kube_state_metrics_selector" toml:"kube_state_metrics_selector" yaml:"kube_state_metrics_selector"`                         // service label selector, blank=none
+	KSMMetricsPort              string                  `mapstructure:"kube_state_metrics_metrics_port" json:"kube_state_metrics_metrics_port" toml:"kube_state_metrics_metrics_port" yaml:"kube_state_metrics_metrics_port"`         // service port name or number
+	KSMTelemetryPort            string                  `mapstructure:"kube_state_metrics_telemetry_port" json:"kube_state_metrics_telemetry_port" toml:"kube_state_metrics_telemetry_port" yaml:"kube_state_metrics_telemetry_port"` // service port name or number, blank=disabled
+	MSInterval                  string                  `mapstructure:"metrics_server_interval" json:"metrics_server_interval" toml:"metrics_server_interval" yaml:"metrics_server_interval"`                                         // blank=interval
+	MSOffset                    string                  `mapstructure:"metrics_server_offset" json:"metrics_server_offset" toml:"metrics_server_offset" yaml:"metrics_server_offset"`                                                 // blank=none
+	APIServerInterval           string                  `mapstructure:"api_server_interval" json:"api_server_interval" toml:"api_server_interval" yaml:"api_server_interval"`                                                         // blank=interval
+	APIServerOffset             string                  `mapstructure:"api_server_offset" json:"api_server_offset" toml:"api_server_offset" yaml:"api_server_offset"`                                                                 // blank=none
+	ControlPlaneInterval        string                  `mapstructure:"control_plane_interval" json:"control_plane_interval" toml:"control_plane_interval" yaml:"control_plane_interval"`                                             // blank=interval
+	ControlPlaneOffset          string                  `mapstructure:"control_plane_offset" json:"control_plane_offset" toml:"control_plane_offset" yaml:"control_plane_offset"`                                                     // blank=none
+	EtcdCertFile                string                  `mapstructure:"etcd_cert_file" json:"etcd_cert_file" toml:"etcd_cert_file" yaml:"etcd_cert_file"`                                                                             // default etcd component client cert
+	EtcdKeyFile                 string                  `mapstructure:"etcd_key_file" json:"etcd_key_file" toml:"etcd_key_file" yaml:"etcd_key_file"`
+	EtcdCAFile                  string                  `mapstructure:"etcd_ca_file" json:"etcd_ca_file" toml:"etcd_ca_file" yaml:"etcd_ca_file"`
+	ObjectsInterval             string                  `mapstructure:"objects_interval" json:"objects_interval" toml:"objects_interval" yaml:"objects_interval"`             // blank=interval
+	ObjectsOffset               string                  `mapstructure:"objects_offset" json:"objects_offset" toml:"objects_offset" yaml:"objects_offset"`                     // blank=none
+	PrometheusInterval          string                  `mapstructure:"prometheus_interval" json:"prometheus_interval" toml:"prometheus_interval" yaml:"prometheus_interval"` // blank=interval
+	PrometheusOffset            string                  `mapstructure:"prometheus_offset" json:"prometheus_offset" toml:"prometheus_offset" yaml:"prometheus_offset"`         // blank=none
+	AlignInterval               bool                    `mapstructure:"align_interval" json:"align_interval" toml:"align_interval" yaml:"align_interval"`
+	Jitter                      string                  `mapstructure:"jitter" json:"jitter" toml:"jitter" yaml:"jitter"`                                 // blank=none
+	OverrunPolicy               string                  `mapstructure:"overrun_policy" json:"overrun_policy" toml:"overrun_policy" yaml:"overrun_policy"` // skip|queue|cancel
+	NodePoolSize                uint                    `mapstructure:"node_pool_size" json:"node_pool_size" toml:"node_pool_size" yaml:"node_pool_size"`
+	URL                         string                  `mapstructure:"api_url" json:"api_url" toml:"api_url" yaml:"api_url"`
+	CAFile                      string                  `mapstructure:"api_ca_file" json:"api_ca_file" toml:"api_ca_file" yaml:"api_ca_file"`
+	APITimelimit                string                  `mapstructure:"api_timelimit" json:"api_timelimit" toml:"api_timelimit" yaml:"api_timelimit"`
+	LocalNode                   bool                    `mapstructure:"local_node" json:"local_node" toml:"local_node" yaml:"local_node"`                     // collect only the node the agent is running on (daemonset)
+	NodeName                    string                  `mapstructure:"node_name" json:"node_name" toml:"node_name" yaml:"node_name"`                         // blank=NODE_NAME env var
+	KubeletMode                 string                  `mapstructure:"kubelet_mode" json:"kubelet_mode" toml:"kubelet_mode" yaml:"kubelet_mode"`             // proxy|direct
+	KubeletPort                 uint                    `mapstructure:"kubelet_port" json:"kubelet_port" toml:"kubelet_port" yaml:"kubelet_port"`             // 0=node kubelet endpoint port
+	KubeletCAFile               string                  `mapstructure:"kubelet_ca_file" json:"kubelet_ca_file" toml:"kubelet_ca_file" yaml:"kubelet_ca_file"` // blank=api_ca_file
+	KubeletInsecure             bool                    `mapstructure:"kubelet_insecure_skip_verify" json:"kubelet_insecure_skip_verify" toml:"kubelet_insecure_skip_verify" yaml:"kubelet_insecure_skip_verify"`
+	EnableLeaderElection        bool                    `mapstructure:"enable_leader_election" json:"enable_leader_election" toml:"enable_leader_election" yaml:"enable_leader_election"`
+	LeaderElectionName          string                  `mapstructure:"leader_election_name" json:"leader_election_name" toml:"leader_election_name" yaml:"leader_election_name"`
+	LeaderElectionNS            string                  `mapstructure:"leader_election_namespace" json:"leader_election_namespace" toml:"leader_election_namespace" yaml:"leader_election_namespace"` // blank=agent namespace
+	EnableSharding              bool                    `mapstructure:"enable_sharding" json:"enable_sharding" toml:"enable_sharding" yaml:"enable_sharding"`
+	ShardMembership             string                  `mapstructure:"shard_membership" json:"shard_membership" toml:"shard_membership" yaml:"shard_membership"` // lease|statefulset
+	ShardGroup                  string                  `mapstructure:"shard_group" json:"shard_group" toml:"shard_group" yaml:"shard_group"`
+	ShardNS                     string                  `mapstructure:"shard_namespace" json:"shard_namespace" toml:"shard_namespace" yaml:"shard_namespace"` // blank=agent namespace
+	ShardReplicas               uint                    `mapstructure:"shard_replicas" json:"shard_replicas" toml:"shard_replicas" yaml:"shard_replicas"`     // statefulset, 0=statefulset spec.replicas
+	Collectors                  []CollectorConfig       `json:"collectors" toml:"collectors" yaml:"collectors"`                                               // blank=derived from enable_* settings
+	ControlPlane                []ControlPlaneComponent `mapstructure:"control_plane" json:"control_plane" toml:"control_plane" yaml:"control_plane"`         // blank=default components
 }
 
-// LabelFilters defines labels to include and exclude
-type LabelFilters struct {
-	Exclude map[string]string `json:"exclude" toml:"exclude" yaml:"exclude"`
-	Include map[string]string `json:"include" toml:"include" yaml:"include"`
+// ControlPlaneComponent defines a control plane component (e.g. kube-scheduler,
+// etcd) scraped by the control plane collector, targets are the running pods
+// matching the selector or the static endpoints
+type ControlPlaneComponent struct {
+	Name               string   `mapstructure:"name" json:"name" toml:"name" yaml:"name"`                                                                 // component tag
+	Namespace          string   `mapstructure:"namespace" json:"namespace" toml:"namespace" yaml:"namespace"`                                             // blank=kube-system
+	Selector           string   `mapstructure:"selector" json:"selector" toml:"selector" yaml:"selector"`                                                 // pod label selector
+	Endpoints          []string `mapstructure:"endpoints" json:"endpoints" toml:"endpoints" yaml:"endpoints"`                                             // static host:port or urls, used instead of the selector
+	Port               uint     `mapstructure:"port" json:"port" toml:"port" yaml:"port"`                                                                 // metrics port of the pods
+	Scheme             string   `mapstructure:"scheme" json:"scheme" toml:"scheme" yaml:"scheme"`                                                         // http|https, blank=https unless auth is none
+	Path               string   `mapstructure:"path" json:"path" toml:"path" yaml:"path"`                                                                 // blank=/metrics
+	Auth               string   `mapstructure:"auth" json:"auth" toml:"auth" yaml:"auth"`                                                                 // none|token|cert, blank=none
+	CertFile           string   `mapstructure:"cert_file" json:"cert_file" toml:"cert_file" yaml:"cert_file"`                                             // client cert (auth cert)
+	KeyFile            string   `mapstructure:"key_file" json:"key_file" toml:"key_file" yaml:"key_file"`                                                 // client key (auth cert)
+	CAFile             string   `mapstructure:"ca_file" json:"ca_file" toml:"ca_file" yaml:"ca_file"`                                                     // blank=system roots
+	InsecureSkipVerify bool     `mapstructure:"insecure_skip_verify" json:"insecure_skip_verify" toml:"insecure_skip_verify" yaml:"insecure_skip_verify"` // self-signed serving certs
+}
+
+// CollectorConfig defines a collector to run in a cluster
+type CollectorConfig struct {
+	Name     string            `json:"name" toml:"name" yaml:"name"`             // registered collector name
+	Interval string            `json:"interval" toml:"interval" yaml:"interval"` // blank=cluster interval
+	Offset   string            `json:"offset" toml:"offset" yaml:"offset"`       // blank=none
+	Options  map[string]string `json:"options" toml:"options" yaml:"options"`    // collector specific options
 }
 
 // Circonus defines the circonus specific configuration options
@@ -122,6 +214,12 @@ func Validate() error {
 	return nil
 }
 
+var (
+	statConfig     *Config
+	statConfigmu   sync.Mutex
+	statConfigOnce sync.Once
+)
+
 // StatConfig adds the running config to the app stats
 func StatConfig() error {
 	cfg, err := getConfig()
@@ -144,9 +242,18 @@ func StatConfig() error {
 		}
 	}
 
-	expvar.Publish("config", expvar.Func(func() interface{} {
-		return &cfg
-	}))
+	statConfigmu.Lock()
+	statConfig = cfg
+	statConfigmu.Unlock()
+
+	// publish once, subsequent calls (e.g. reload) update the published config
+	statConfigOnce.Do(func() {
+		expvar.Publish("config", expvar.Func(func() interface{} {
+			statConfigmu.Lock()
+			defer statConfigmu.Unlock()
+			return statConfig
+		}))
+	})
 
 	return nil
 }
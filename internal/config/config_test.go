@@ -81,4 +81,10 @@ func TestStatConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected no error, got %s", err)
 	}
+
+	t.Log("again (reload)")
+	err = StatConfig()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
 }
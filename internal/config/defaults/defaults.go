@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
 )
@@ -49,9 +50,10 @@ const (
 
 	// General defaults
 
-	Debug     = false
-	LogLevel  = "info"
-	LogPretty = false
+	Debug       = false
+	LogLevel    = "info"
+	LogPretty   = false
+	WatchConfig = false
 
 	// Kubernetes cluster
 
@@ -64,25 +66,106 @@ const (
 		namespace of ck8sa: /var/run/secrets/kubernetes.io/serviceaccount/namespace
 	*/
 
-	K8SName                   = ""
-	K8SInterval               = "1m"
-	K8SAPIURL                 = "https://kubernetes"
-	K8SAPICAFile              = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
-	K8SBearerToken            = ""
-	K8SBearerTokenFile        = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
-	K8SEnableEvents           = false
-	K8SEnableKubeStateMetrics = false
-	K8SEnableMetricsServer    = false
-	K8SEnableNodes            = true
-	K8SEnableNodeStats        = true
-	K8SEnableNodeMetrics      = true
-	K8SEnableCadvisorMetrics  = false
-	K8SNodeSelector           = "" // blank=all
-	K8SIncludePods            = true
-	K8SPodLabelKey            = "" // blank=all
-	K8SPodLabelVal            = "" // blank=all
-	K8SIncludeContainers      = false
-	K8SAPITimelimit           = "10s"
+	K8SName                        = ""
+	K8SInterval                    = "1m"
+	K8SNodesInterval               = "" // blank=K8SInterval
+	K8SNodesOffset                 = ""
+	K8SKSMInterval                 = "" // blank=K8SInterval
+	K8SKSMOffset                   = ""
+	K8SKSMNamespace                = "" // blank=all
+	K8SKSMService                  = "kube-state-metrics"
+	K8SKSMSelector                 = ""
+	K8SKSMMetricsPort              = "http-metrics"
+	K8SKSMTelemetryPort            = "telemetry"
+	K8SMSInterval                  = "" // blank=K8SInterval
+	K8SMSOffset                    = ""
+	K8SAPIServerInterval           = "" // blank=K8SInterval
+	K8SAPIServerOffset             = ""
+	K8SControlPlaneInterval        = "" // blank=K8SInterval
+	K8SControlPlaneOffset          = ""
+	K8SEtcdCertFile                = "" // blank=etcd not scraped by default
+	K8SEtcdKeyFile                 = ""
+	K8SEtcdCAFile                  = ""
+	K8SObjectsInterval             = "" // blank=K8SInterval
+	K8SObjectsOffset               = ""
+	K8SPrometheusInterval          = "" // blank=K8SInterval
+	K8SPrometheusOffset            = ""
+	K8SAlignInterval               = false
+	K8SJitter                      = "" // blank=none
+	K8SOverrunPolicy               = "skip"
+	K8SAPIURL                      = "https://kubernetes"
+	K8SAPICAFile                   = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	K8SBearerToken                 = ""
+	K8SBearerTokenFile             = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+	K8SEnableEvents                = false
+	K8SEventsCheckpointFile        = "" // blank=events before agent start are skipped
+	K8SEventsCheckpointConfigMap   = ""
+	K8SEventsInclude               = "" // blank=all events
+	K8SEventsExclude               = ""
+	K8SEventsAnnotations           = false
+	K8SEventsAnnotationMatch       = "type=Warning,reason=ScalingReplicaSet" // warnings and deployment rollouts
+	K8SEventsAnnotationTitle       = "{{.Reason}} {{.Kind}} {{.Namespace}}/{{.Name}}"
+	K8SEventsAnnotationCategory    = "kubernetes {{.Cluster}}"
+	K8SEventsAnnotationDescription = "{{.Type}}: {{.Message}}"
+	K8SEventsAnnotationWindow      = "1m"
+	K8SEventsAnnotationRateLimit   = uint(10)
+	K8SEnableKubeStateMetrics      = false
+	K8SEnableMetricsServer         = false
+	K8SEnableAPIServer             = false
+	K8SEnableControlPlane          = false
+	K8SEnableObjects               = false
+	K8SEnablePrometheus            = false
+	K8SPrometheusAnnotationPrefix  = "prometheus.io"
+	K8SPrometheusScrapeTimeout     = "10s"
+	K8SPrometheusConcurrency       = uint(10)
+	K8SEnableNodes                 = true
+	K8SEnableNodeStats             = true
+	K8SEnableNodeMetrics           = true
+	K8SEnableCadvisorMetrics       = false
+	K8SEnableResourceMetrics       = false
+	K8SEnableProbeMetrics          = false
+	K8SNodeSelector                = "" // blank=all
+	K8SIncludePods                 = true
+	K8SPodLabelKey                 = "" // blank=all
+	K8SPodLabelVal                 = "" // blank=all
+	K8SPodSelector                 = "" // blank=all
+	K8SNamespaceInclude            = "" // blank=all
+	K8SNamespaceExclude            = ""
+	K8SCollectAnnotation           = "circonus.com/collect"
+	K8STagAllow                    = "" // blank=all
+	K8STagDeny                     = "pod-template-hash,controller-revision-hash"
+	K8STagRename                   = ""
+	K8SCounterRates                = "" // blank=none
+	K8SCounterDeltas               = "" // blank=none
+	K8SIncludeContainers           = false
+	K8SAPITimelimit                = "10s"
+	K8SLocalNode                   = false
+	K8SNodeName                    = "" // blank=K8SNodeNameEnv
+	K8SNodeNameEnv                 = "NODE_NAME"
+	K8SKubeletMode                 = "proxy"
+	K8SKubeletPort                 = uint(0) // 0=node kubelet endpoint port
+	K8SKubeletCAFile               = ""      // blank=K8SAPICAFile
+	K8SKubeletInsecure             = false
+	K8SKubeletDefaultPort          = 10250
+	K8SEnableLeaderElection        = false
+	K8SLeaderElectionName          = release.NAME
+	K8SLeaderElectionNS            = "" // blank=agent namespace, from K8SNamespaceFile
+	K8SEnableSharding              = false
+	K8SShardMembership             = "lease"
+	K8SShardGroup                  = release.NAME
+	K8SShardNS                     = "" // blank=agent namespace, from K8SNamespaceFile
+	K8SShardReplicas               = uint(0)
+
+	// K8SNamespaceFile is the namespace the agent is running in (when deployed in-cluster)
+	K8SNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// K8SLeaseDuration is the duration standby replicas wait before acquiring leadership
+	K8SLeaseDuration = 15 * time.Second
+	// K8SLeaseRenewDeadline is the duration the leader retries refreshing leadership before giving up
+	K8SLeaseRenewDeadline = 10 * time.Second
+	// K8SLeaseRetryPeriod is the duration between leader election attempts
+	K8SLeaseRetryPeriod = 2 * time.Second
+	// K8SShardRenewInterval is the duration between shard membership refreshes
+	K8SShardRenewInterval = 5 * time.Second
 )
 
 var (
@@ -125,6 +125,81 @@ const (
 	// K8SInterval collection interval
 	K8SInterval = "kubernetes.interval"
 
+	// K8SNodesInterval node collection interval (blank=K8SInterval)
+	K8SNodesInterval = "kubernetes.nodes_interval"
+
+	// K8SNodesOffset delay before first node collection, to stagger collectors
+	K8SNodesOffset = "kubernetes.nodes_offset"
+
+	// K8SKSMInterval kube-state-metrics collection interval (blank=K8SInterval)
+	K8SKSMInterval = "kubernetes.kube_state_metrics_interval"
+
+	// K8SKSMOffset delay before first kube-state-metrics collection, to stagger collectors
+	K8SKSMOffset = "kubernetes.kube_state_metrics_offset"
+
+	// K8SKSMNamespace namespace of the kube-state-metrics service(s) (blank=all namespaces)
+	K8SKSMNamespace = "kubernetes.kube_state_metrics_namespace"
+
+	// K8SKSMService name of the kube-state-metrics service(s)
+	K8SKSMService = "kubernetes.kube_state_metrics_service"
+
+	// K8SKSMSelector label selector for the kube-state-metrics service(s)
+	K8SKSMSelector = "kubernetes.kube_state_metrics_selector"
+
+	// K8SKSMMetricsPort kube-state-metrics metrics service port name or number
+	K8SKSMMetricsPort = "kubernetes.kube_state_metrics_metrics_port"
+
+	// K8SKSMTelemetryPort kube-state-metrics telemetry service port name or number
+	K8SKSMTelemetryPort = "kubernetes.kube_state_metrics_telemetry_port"
+
+	// K8SMSInterval metrics-server collection interval (blank=K8SInterval)
+	K8SMSInterval = "kubernetes.metrics_server_interval"
+
+	// K8SMSOffset delay before first metrics-server collection, to stagger collectors
+	K8SMSOffset = "kubernetes.metrics_server_offset"
+
+	// K8SAPIServerInterval api server metrics collection interval (blank=K8SInterval)
+	K8SAPIServerInterval = "kubernetes.api_server_interval"
+
+	// K8SAPIServerOffset delay before first api server metrics collection, to stagger collectors
+	K8SAPIServerOffset = "kubernetes.api_server_offset"
+
+	// K8SControlPlaneInterval control plane collection interval (blank=K8SInterval)
+	K8SControlPlaneInterval = "kubernetes.control_plane_interval"
+
+	// K8SControlPlaneOffset delay before first control plane collection, to stagger collectors
+	K8SControlPlaneOffset = "kubernetes.control_plane_offset"
+
+	// K8SEtcdCertFile client certificate for the default etcd control plane component
+	K8SEtcdCertFile = "kubernetes.etcd_cert_file"
+
+	// K8SEtcdKeyFile client key for the default etcd control plane component
+	K8SEtcdKeyFile = "kubernetes.etcd_key_file"
+
+	// K8SEtcdCAFile CA certificate for the default etcd control plane component
+	K8SEtcdCAFile = "kubernetes.etcd_ca_file"
+
+	// K8SPrometheusInterval prometheus annotated target collection interval (blank=K8SInterval)
+	K8SPrometheusInterval = "kubernetes.prometheus_interval"
+
+	// K8SPrometheusOffset delay before first prometheus annotated target collection, to stagger collectors
+	K8SPrometheusOffset = "kubernetes.prometheus_offset"
+
+	// K8SObjectsInterval object state collection interval (blank=K8SInterval)
+	K8SObjectsInterval = "kubernetes.objects_interval"
+
+	// K8SObjectsOffset delay before first object state collection, to stagger collectors
+	K8SObjectsOffset = "kubernetes.objects_offset"
+
+	// K8SAlignInterval align collection starts to wall clock boundaries of the interval
+	K8SAlignInterval = "kubernetes.align_interval"
+
+	// K8SJitter maximum random delay added to collection starts, to spread a fleet of agents
+	K8SJitter = "kubernetes.jitter"
+
+	// K8SOverrunPolicy what to do when a collection is still running when the next is due (skip|queue|cancel)
+	K8SOverrunPolicy = "kubernetes.overrun_policy"
+
 	// K8SAPIURL base k8s api url
 	K8SAPIURL = "kubernetes.api_url"
 
@@ -148,15 +223,75 @@ const (
 	// K8SEnableCadvisorMetrics - kublet /metrics/cadvisor metrics
 	K8SEnableCadvisorMetrics = "kubernetes.enable_cadvisor_metrics"
 
+	// K8SEnableResourceMetrics - kublet /metrics/resource metrics (lightweight alternative to /stats/summary)
+	K8SEnableResourceMetrics = "kubernetes.enable_resource_metrics"
+
+	// K8SEnableProbeMetrics - kublet /metrics/probes liveness, readiness, and startup probe results
+	K8SEnableProbeMetrics = "kubernetes.enable_probe_metrics"
+
 	// K8SEnableEvents enable events
 	K8SEnableEvents = "kubernetes.enable_events"
 
+	// K8SEventsCheckpointFile file to persist the event watcher checkpoint to
+	K8SEventsCheckpointFile = "kubernetes.events_checkpoint_file"
+
+	// K8SEventsCheckpointConfigMap configmap (in the agent namespace) to persist the event watcher checkpoint to
+	K8SEventsCheckpointConfigMap = "kubernetes.events_checkpoint_configmap"
+
+	// K8SEventsInclude events to collect, comma separated field=pattern (namespace, type, reason, kind)
+	K8SEventsInclude = "kubernetes.events_include"
+
+	// K8SEventsExclude events to drop, comma separated field=pattern (namespace, type, reason, kind)
+	K8SEventsExclude = "kubernetes.events_exclude"
+
+	// K8SEventsAnnotations create circonus annotations for events
+	K8SEventsAnnotations = "kubernetes.events_annotations"
+
+	// K8SEventsAnnotationMatch events to annotate, comma separated field=pattern (namespace, type, reason, kind), events matching any are annotated
+	K8SEventsAnnotationMatch = "kubernetes.events_annotation_match"
+
+	// K8SEventsAnnotationTitle annotation title template
+	K8SEventsAnnotationTitle = "kubernetes.events_annotation_title"
+
+	// K8SEventsAnnotationCategory annotation category template
+	K8SEventsAnnotationCategory = "kubernetes.events_annotation_category"
+
+	// K8SEventsAnnotationDescription annotation description template
+	K8SEventsAnnotationDescription = "kubernetes.events_annotation_description"
+
+	// K8SEventsAnnotationWindow events with the same annotation title and category within the window are aggregated
+	K8SEventsAnnotationWindow = "kubernetes.events_annotation_window"
+
+	// K8SEventsAnnotationRateLimit maximum annotations created per minute
+	K8SEventsAnnotationRateLimit = "kubernetes.events_annotation_rate_limit"
+
 	// K8SEnableKubeStateMetrics enable kube-state-metrics
 	K8SEnableKubeStateMetrics = "kubernetes.enable_kube_state_metrics"
 
 	// K8SEnableMetricsServer enable metrics-server
 	K8SEnableMetricsServer = "kubernetes.enable_metrics_server"
 
+	// K8SEnableAPIServer enable api server metrics (/metrics)
+	K8SEnableAPIServer = "kubernetes.enable_api_server"
+
+	// K8SEnableControlPlane enable control plane component (scheduler, controller-manager, etcd, coredns, kube-proxy) metrics
+	K8SEnableControlPlane = "kubernetes.enable_control_plane"
+
+	// K8SEnablePrometheus enable scraping of prometheus.io annotated pods and services
+	K8SEnablePrometheus = "kubernetes.enable_prometheus"
+
+	// K8SPrometheusAnnotationPrefix prefix of the scrape, port, path, and scheme annotations
+	K8SPrometheusAnnotationPrefix = "kubernetes.prometheus_annotation_prefix"
+
+	// K8SPrometheusScrapeTimeout timeout of each annotated target scrape
+	K8SPrometheusScrapeTimeout = "kubernetes.prometheus_scrape_timeout"
+
+	// K8SPrometheusConcurrency maximum annotated targets scraped at once
+	K8SPrometheusConcurrency = "kubernetes.prometheus_concurrency"
+
+	// K8SEnableObjects enable the built-in object state collector (kube-state-metrics compatible series)
+	K8SEnableObjects = "kubernetes.enable_objects"
+
 	// K8SIncludePods include pod metrics
 	// NOTE: requires K8SEnableNodes and K8SEnableNodeSummary
 	K8SIncludePods = "kubernetes.include_pod_metrics"
@@ -167,6 +302,33 @@ const (
 	// K8SPodLabelVal include pod if label value matches
 	K8SPodLabelVal = "kubernetes.pod_label_val"
 
+	// K8SPodSelector include pods matching label selector
+	K8SPodSelector = "kubernetes.pod_selector"
+
+	// K8SNamespaceInclude include only these namespaces (comma separated)
+	K8SNamespaceInclude = "kubernetes.namespace_include"
+
+	// K8SNamespaceExclude exclude these namespaces (comma separated)
+	K8SNamespaceExclude = "kubernetes.namespace_exclude"
+
+	// K8SCollectAnnotation pods and namespaces with annotation set to "false" are not collected
+	K8SCollectAnnotation = "kubernetes.collect_annotation"
+
+	// K8STagAllow label key patterns to turn into stream tags (comma separated)
+	K8STagAllow = "kubernetes.tag_allow"
+
+	// K8STagDeny label key patterns to drop (comma separated)
+	K8STagDeny = "kubernetes.tag_deny"
+
+	// K8STagRename label key renames, from=to (comma separated)
+	K8STagRename = "kubernetes.tag_rename"
+
+	// K8SCounterRates counter metric name patterns to emit per second rates for (comma separated)
+	K8SCounterRates = "kubernetes.counter_rates"
+
+	// K8SCounterDeltas counter metric name patterns to emit deltas for (comma separated)
+	K8SCounterDeltas = "kubernetes.counter_deltas"
+
 	// K8SIncludeContainers include container metrics
 	// NOTE: will not be included unless include_pods is true
 	K8SIncludeContainers = "kubernetes.include_container_metrics"
@@ -181,6 +343,48 @@ const (
 	// K8SAPITimelimit amount of time to wait for a complete response from api-server
 	K8SAPITimelimit = "kubernetes.api_timelimit"
 
+	// K8SLocalNode collect only the node the agent is running on (daemonset deployment)
+	K8SLocalNode = "kubernetes.local_node"
+
+	// K8SNodeName name of the node the agent is running on (blank=NODE_NAME env var)
+	K8SNodeName = "kubernetes.node_name"
+
+	// K8SKubeletMode how to reach kubelets, via the api server proxy or directly (proxy|direct)
+	K8SKubeletMode = "kubernetes.kubelet_mode"
+
+	// K8SKubeletPort kubelet port for direct mode (0=node kubelet endpoint port)
+	K8SKubeletPort = "kubernetes.kubelet_port"
+
+	// K8SKubeletCAFile CA cert file to verify kubelets in direct mode (blank=K8SAPICAFile)
+	K8SKubeletCAFile = "kubernetes.kubelet_ca_file"
+
+	// K8SKubeletInsecure skip verification of kubelet certificates in direct mode
+	K8SKubeletInsecure = "kubernetes.kubelet_insecure_skip_verify"
+
+	// K8SEnableLeaderElection only the replica holding the lease collects and submits metrics
+	K8SEnableLeaderElection = "kubernetes.enable_leader_election"
+
+	// K8SLeaderElectionName name of the lease used for leader election
+	K8SLeaderElectionName = "kubernetes.leader_election_name"
+
+	// K8SLeaderElectionNS namespace of the lease used for leader election (blank=agent namespace)
+	K8SLeaderElectionNS = "kubernetes.leader_election_namespace"
+
+	// K8SEnableSharding split node collection across agent replicas
+	K8SEnableSharding = "kubernetes.enable_sharding"
+
+	// K8SShardMembership how shard members are determined (lease|statefulset)
+	K8SShardMembership = "kubernetes.shard_membership"
+
+	// K8SShardGroup name of the shard group, used to label member leases and name the statefulset
+	K8SShardGroup = "kubernetes.shard_group"
+
+	// K8SShardNS namespace of the shard member leases or statefulset (blank=agent namespace)
+	K8SShardNS = "kubernetes.shard_namespace"
+
+	// K8SShardReplicas number of statefulset replicas (0=read statefulset spec.replicas)
+	K8SShardReplicas = "kubernetes.shard_replicas"
+
 	//
 	// Kubernetes clusters (multiple, use either kubernetes or clusters, not both)
 	//
@@ -205,6 +409,9 @@ const (
 	// Debug enables debug messages
 	Debug = "debug"
 
+	// WatchConfig reloads the configuration when the config file changes
+	WatchConfig = "watch_config"
+
 	//
 	// Informational
 	// NOTE: these ARE NOT included in the configuration file as they
@@ -0,0 +1,206 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package controlplane
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	authNone  = "none"
+	authToken = "token"
+	authCert  = "cert"
+
+	defaultNamespace = "kube-system"
+	defaultPath      = "/metrics"
+)
+
+// defaultComponents are the components scraped when none are configured,
+// selectors match the labels used by kubeadm and most distributions
+var defaultComponents = []config.ControlPlaneComponent{
+	{Name: "kube-scheduler", Selector: "component=kube-scheduler", Port: 10259, Auth: authToken, InsecureSkipVerify: true},
+	{Name: "kube-controller-manager", Selector: "component=kube-controller-manager", Port: 10257, Auth: authToken, InsecureSkipVerify: true},
+	{Name: "etcd", Selector: "component=etcd", Port: 2379, Auth: authCert},
+	{Name: "coredns", Selector: "k8s-app=kube-dns", Port: 9153, Auth: authNone},
+	{Name: "kube-proxy", Selector: "k8s-app=kube-proxy", Port: 10249, Auth: authNone},
+}
+
+// component is a normalized control plane component
+type component struct {
+	config.ControlPlaneComponent
+	tls *tls.Config // nil=http
+}
+
+// target is a single metrics endpoint of a component
+type target struct {
+	instance string // pod name or static host:port
+	url      string
+}
+
+// configured returns the components to scrape, the default components when
+// none are configured. The default etcd component uses the etcd certificate
+// settings and is skipped when no client certificate is configured.
+func configured(cfg *config.Cluster) []config.ControlPlaneComponent {
+	if len(cfg.ControlPlane) > 0 {
+		return cfg.ControlPlane
+	}
+
+	list := make([]config.ControlPlaneComponent, 0, len(defaultComponents))
+	for _, c := range defaultComponents {
+		if c.Auth == authCert {
+			if cfg.EtcdCertFile == "" {
+				continue
+			}
+			c.CertFile = cfg.EtcdCertFile
+			c.KeyFile = cfg.EtcdKeyFile
+			c.CAFile = cfg.EtcdCAFile
+		}
+		list = append(list, c)
+	}
+	return list
+}
+
+// normalize validates a component and applies the defaults for
+// namespace, path, auth, and scheme
+func normalize(c config.ControlPlaneComponent) (config.ControlPlaneComponent, error) {
+	if c.Name == "" {
+		return c, errors.New("component name required")
+	}
+	if c.Selector == "" && len(c.Endpoints) == 0 {
+		return c, errors.Errorf("component %s: selector or endpoints required", c.Name)
+	}
+	if c.Selector != "" && c.Port == 0 {
+		return c, errors.Errorf("component %s: port required with selector", c.Name)
+	}
+
+	if c.Namespace == "" {
+		c.Namespace = defaultNamespace
+	}
+	if c.Path == "" {
+		c.Path = defaultPath
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		c.Path = "/" + c.Path
+	}
+
+	c.Auth = strings.ToLower(c.Auth)
+	switch c.Auth {
+	case "":
+		c.Auth = authNone
+	case authNone, authToken:
+	case authCert:
+		if c.CertFile == "" || c.KeyFile == "" {
+			return c, errors.Errorf("component %s: cert_file and key_file required for cert auth", c.Name)
+		}
+	default:
+		return c, errors.Errorf("component %s: invalid auth (%s) none|token|cert", c.Name, c.Auth)
+	}
+
+	c.Scheme = strings.ToLower(c.Scheme)
+	switch c.Scheme {
+	case "":
+		c.Scheme = "https"
+		if c.Auth == authNone {
+			c.Scheme = "http"
+		}
+	case "http":
+		if c.Auth != authNone {
+			// never send credentials in the clear
+			return c, errors.Errorf("component %s: %s auth requires https", c.Name, c.Auth)
+		}
+	case "https":
+	default:
+		return c, errors.Errorf("component %s: invalid scheme (%s) http|https", c.Name, c.Scheme)
+	}
+
+	return c, nil
+}
+
+// tlsConfig returns the tls configuration for an https component
+func tlsConfig(c config.ControlPlaneComponent) (*tls.Config, error) {
+	if c.Scheme != "https" {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify} //nolint:gosec
+
+	if c.CAFile != "" {
+		cert, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "component %s: reading ca file", c.Name)
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(cert) {
+			return nil, errors.Errorf("component %s: unable to add CA Certificate to x509 cert pool", c.Name)
+		}
+		tlsCfg.RootCAs = cp
+	}
+
+	if c.Auth == authCert {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "component %s: loading client certificate", c.Name)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
+
+// staticTargets returns the targets for the static endpoints of a component,
+// endpoints are either host:port or complete urls
+func staticTargets(c config.ControlPlaneComponent) []target {
+	targets := make([]target, 0, len(c.Endpoints))
+	for _, ep := range c.Endpoints {
+		ep = strings.TrimSpace(ep)
+		if ep == "" {
+			continue
+		}
+		if strings.Contains(ep, "://") {
+			u, err := url.Parse(ep)
+			if err != nil || u.Host == "" {
+				continue
+			}
+			if u.Path == "" {
+				u.Path = c.Path
+			}
+			targets = append(targets, target{instance: u.Host, url: u.String()})
+			continue
+		}
+		targets = append(targets, target{instance: ep, url: c.Scheme + "://" + ep + c.Path})
+	}
+	return targets
+}
+
+// podTargets returns the targets for the running pods of a component,
+// in pod name order
+func podTargets(c config.ControlPlaneComponent, pods []corev1.Pod) []target {
+	targets := make([]target, 0, len(pods))
+	for _, p := range pods {
+		if p.Status.Phase != corev1.PodRunning || p.Status.PodIP == "" {
+			continue
+		}
+		hostPort := net.JoinHostPort(p.Status.PodIP, strconv.FormatUint(uint64(c.Port), 10))
+		targets = append(targets, target{
+			instance: p.Name,
+			url:      fmt.Sprintf("%s://%s%s", c.Scheme, hostPort, c.Path),
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].instance < targets[j].instance })
+	return targets
+}
@@ -0,0 +1,119 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package controlplane
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigured(t *testing.T) {
+	list := configured(&config.Cluster{})
+	for _, c := range list {
+		if c.Name == "etcd" {
+			t.Fatal("expected etcd to be skipped without a client certificate")
+		}
+	}
+	if len(list) != len(defaultComponents)-1 {
+		t.Fatalf("expected %d components, got %d", len(defaultComponents)-1, len(list))
+	}
+
+	list = configured(&config.Cluster{EtcdCertFile: "c.pem", EtcdKeyFile: "k.pem"})
+	if len(list) != len(defaultComponents) {
+		t.Fatalf("expected %d components, got %d", len(defaultComponents), len(list))
+	}
+
+	list = configured(&config.Cluster{ControlPlane: []config.ControlPlaneComponent{{Name: "x"}}})
+	if len(list) != 1 || list[0].Name != "x" {
+		t.Fatalf("expected configured components, got %v", list)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		desc      string
+		component config.ControlPlaneComponent
+		scheme    string
+		shouldErr bool
+	}{
+		{"no name", config.ControlPlaneComponent{Selector: "a=b", Port: 1}, "", true},
+		{"no selector or endpoints", config.ControlPlaneComponent{Name: "x"}, "", true},
+		{"selector no port", config.ControlPlaneComponent{Name: "x", Selector: "a=b"}, "", true},
+		{"invalid auth", config.ControlPlaneComponent{Name: "x", Endpoints: []string{"h:1"}, Auth: "basic"}, "", true},
+		{"cert no key", config.ControlPlaneComponent{Name: "x", Endpoints: []string{"h:1"}, Auth: "cert", CertFile: "c"}, "", true},
+		{"token over http", config.ControlPlaneComponent{Name: "x", Endpoints: []string{"h:1"}, Auth: "token", Scheme: "http"}, "", true},
+		{"none", config.ControlPlaneComponent{Name: "x", Selector: "a=b", Port: 1}, "http", false},
+		{"token", config.ControlPlaneComponent{Name: "x", Selector: "a=b", Port: 1, Auth: "Token"}, "https", false},
+		{"none over https", config.ControlPlaneComponent{Name: "x", Endpoints: []string{"h:1"}, Scheme: "https"}, "https", false},
+	}
+
+	for _, test := range tests {
+		c, err := normalize(test.component)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("%s: expected error", test.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error (%s)", test.desc, err)
+			continue
+		}
+		if c.Scheme != test.scheme {
+			t.Errorf("%s: expected scheme %s, got %s", test.desc, test.scheme, c.Scheme)
+		}
+		if c.Namespace != defaultNamespace || c.Path != defaultPath {
+			t.Errorf("%s: expected default namespace and path, got %s %s", test.desc, c.Namespace, c.Path)
+		}
+	}
+}
+
+func TestStaticTargets(t *testing.T) {
+	c := config.ControlPlaneComponent{
+		Scheme:    "https",
+		Path:      "/metrics",
+		Endpoints: []string{"10.0.0.1:2379", "", "http://10.0.0.2:10249", "https://10.0.0.3:9000/custom"},
+	}
+
+	expected := []target{
+		{instance: "10.0.0.1:2379", url: "https://10.0.0.1:2379/metrics"},
+		{instance: "10.0.0.2:10249", url: "http://10.0.0.2:10249/metrics"},
+		{instance: "10.0.0.3:9000", url: "https://10.0.0.3:9000/custom"},
+	}
+
+	targets := staticTargets(c)
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+	for i, e := range expected {
+		if targets[i] != e {
+			t.Errorf("expected %v, got %v", e, targets[i])
+		}
+	}
+}
+
+func TestPodTargets(t *testing.T) {
+	c := config.ControlPlaneComponent{Scheme: "http", Path: "/metrics", Port: 9153}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns-b"}, Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns-a"}, Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "coredns-c"}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+	}
+
+	targets := podTargets(c, pods)
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].instance != "coredns-a" {
+		t.Fatalf("expected targets sorted by pod name, got %s first", targets[0].instance)
+	}
+	if expected := "http://10.0.0.2:9153/metrics"; targets[1].url != expected {
+		t.Fatalf("expected %s, got %s", expected, targets[1].url)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package controlplane is the control plane collector, it collects the
+// prometheus metrics of the control plane components (kube-scheduler,
+// kube-controller-manager, etcd, coredns, kube-proxy) discovered by pod
+// label selector or configured as static endpoints
+package controlplane
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxConcurrent is the maximum number of targets scraped at once
+const maxConcurrent = 5
+
+type ControlPlane struct {
+	config       *config.Cluster
+	check        *circonus.Check
+	log          zerolog.Logger
+	clientset    *kubernetes.Clientset
+	components   []component
+	running      bool
+	apiTimelimit time.Duration
+	tags         *tagrules.Rules // nil=all labels become tags
+	rates        *rates.Store    // nil=no counter rates or deltas
+	sync.Mutex
+}
+
+func init() {
+	registry.Register("control-plane", func(env registry.Env) (registry.Collector, error) {
+		cp, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		cp.tags = env.Tags
+		cp.rates = env.Rates
+		return cp, nil
+	})
+}
+
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*ControlPlane, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	cp := &ControlPlane{
+		config: cfg,
+		check:  check,
+		log:    parentLog.With().Str("collector", "control-plane").Logger(),
+	}
+
+	for _, cc := range configured(cfg) {
+		c, err := normalize(cc)
+		if err != nil {
+			return nil, errors.Wrap(err, "control plane collector")
+		}
+		tlsCfg, err := tlsConfig(c)
+		if err != nil {
+			return nil, errors.Wrap(err, "control plane collector")
+		}
+		cp.components = append(cp.components, component{ControlPlaneComponent: c, tls: tlsCfg})
+	}
+	if len(cfg.ControlPlane) == 0 && cfg.EtcdCertFile == "" {
+		cp.log.Info().Msg("no etcd client certificate configured, etcd not collected")
+	}
+
+	needPods := false
+	for _, c := range cp.components {
+		if len(c.Endpoints) == 0 {
+			needPods = true
+			break
+		}
+	}
+	if needPods {
+		clientset, err := k8s.NewClientset(cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "control plane collector")
+		}
+		cp.clientset = clientset
+	}
+
+	if cfg.APITimelimit != "" {
+		v, err := time.ParseDuration(cfg.APITimelimit)
+		if err != nil {
+			cp.log.Error().Err(err).Msg("parsing api timelimit, using default")
+		} else {
+			cp.apiTimelimit = v
+		}
+	}
+
+	if cp.apiTimelimit == time.Duration(0) {
+		v, err := time.ParseDuration(defaults.K8SAPITimelimit)
+		if err != nil {
+			cp.log.Fatal().Err(err).Msg("parsing DEFAULT api timelimit")
+		}
+		cp.apiTimelimit = v
+	}
+
+	return cp, nil
+}
+
+func (cp *ControlPlane) ID() string {
+	return "control-plane"
+}
+
+// Collect scrapes the metrics of each control plane component target
+func (cp *ControlPlane) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	cp.Lock()
+	if cp.running {
+		cp.log.Warn().Msg("already running")
+		cp.Unlock()
+		return
+	}
+	cp.running = true
+	cp.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			cp.log.Error().Interface("panic", r).Msg("recover")
+		}
+		cp.Lock()
+		cp.running = false
+		cp.Unlock()
+	}()
+
+	collectStart := time.Now()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrent)
+	for _, c := range cp.components {
+		targets, err := cp.targets(c)
+		if err != nil {
+			cp.log.Warn().Err(err).Str("component", c.Name).Msg("discovering targets")
+			continue
+		}
+		cp.check.AddGauge("collect_control_plane_targets", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "component", Value: c.Name},
+		}, uint64(len(targets)))
+		if len(targets) == 0 {
+			cp.log.Debug().Str("component", c.Name).Msg("no targets")
+			continue
+		}
+		for _, t := range targets {
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(c component, t target) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if err := cp.scrape(ctx, c, t, ts); err != nil {
+					cp.log.Warn().Err(err).Str("component", c.Name).Str("instance", t.instance).Msg("scraping metrics")
+				}
+			}(c, t)
+		}
+	}
+	wg.Wait()
+
+	cp.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_control-plane"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	cp.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("control-plane collect end")
+}
+
+// targets returns the static endpoints of a component, or its running pods
+func (cp *ControlPlane) targets(c component) ([]target, error) {
+	if len(c.Endpoints) > 0 {
+		return staticTargets(c.ControlPlaneComponent), nil
+	}
+	if cp.clientset == nil {
+		return nil, errors.New("no clientset for pod discovery")
+	}
+
+	pods, err := cp.clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: c.Selector})
+	if err != nil {
+		cp.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "pods"},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return nil, errors.Wrap(err, "listing pods")
+	}
+
+	return podTargets(c.ControlPlaneComponent, pods.Items), nil
+}
+
+// scrape collects the metrics of a single component target
+func (cp *ControlPlane) scrape(ctx context.Context, c component, t target, ts *time.Time) error {
+	client, err := k8s.NewAPIClient(c.tls, cp.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, "metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		return errors.Wrap(err, "metrics req")
+	}
+	req = req.WithContext(ctx)
+	if c.Auth == authToken {
+		// only token components get the agent's bearer token, never
+		// http endpoints or endpoints authenticating with client certs
+		req.Header.Add("Authorization", "Bearer "+cp.config.BearerToken)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		cp.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: c.Name},
+		})
+		return errors.Wrap(err, t.url)
+	}
+	defer resp.Body.Close()
+	cp.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "target", Value: c.Name},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		cp.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: c.Name},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return errors.Wrapf(err, "reading response (%s)", resp.Status)
+		}
+		return errors.Errorf("%s %s: %s", t.url, resp.Status, string(data))
+	}
+
+	streamTags := []string{
+		"source:control-plane",
+		"component:" + c.Name,
+		"instance:" + t.instance,
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	measurementTags := []string{}
+
+	if err := promtext.QueueMetrics(ctx, cp.check, cp.log, resp.Body, streamTags, measurementTags, ts, &promtext.Options{Tags: cp.tags, Rates: cp.rates}); err != nil {
+		return errors.Wrap(err, "formatting metrics")
+	}
+
+	return nil
+}
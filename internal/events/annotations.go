@@ -0,0 +1,324 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	apiclient "github.com/circonus-labs/go-apiclient"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// annotationData is the data available to the annotation templates
+type annotationData struct {
+	Cluster   string
+	Namespace string
+	Name      string // involved object name
+	Kind      string // involved object kind
+	Type      string
+	Reason    string
+	Message   string
+	Source    string // reporting component
+	Host      string // reporting host
+	Count     uint64 // occurrences
+}
+
+var templateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// annotator creates Circonus annotations for matching events. Events
+// rendering the same title and category within the aggregation window
+// are combined into a single annotation spanning their occurrences, and
+// annotations over the rate limit are dropped, so an event storm does
+// not flood the annotation api.
+type annotator struct {
+	check       *circonus.Check
+	log         zerolog.Logger
+	cluster     string
+	match       map[string][]*regexp.Regexp // an event matching any field pattern is annotated
+	title       *template.Template
+	category    *template.Template
+	description *template.Template
+	window      time.Duration
+	rateLimit   int         // annotations per minute
+	sent        []time.Time // annotations created in the last minute
+	groups      map[string]*annotationGroup
+	order       []string // group keys in the order they were started
+	sync.Mutex
+}
+
+// annotationGroup is an annotation aggregating event occurrences
+type annotationGroup struct {
+	title       string
+	category    string
+	description string
+	start       time.Time // first occurrence
+	stop        time.Time // last occurrence
+	count       uint64
+	expires     time.Time // when the annotation is created
+}
+
+// newAnnotator returns an annotator, nil when annotations are not enabled
+func newAnnotator(cfg *config.Cluster, check *circonus.Check, parentLog zerolog.Logger) (*annotator, error) {
+	if !cfg.EventsAnnotations {
+		return nil, nil
+	}
+
+	a := &annotator{
+		check:     check,
+		log:       parentLog.With().Str("pkg", "annotations").Logger(),
+		cluster:   cfg.Name,
+		rateLimit: int(cfg.EventsAnnotationRateLimit),
+		groups:    make(map[string]*annotationGroup),
+	}
+
+	match, err := parseFieldPatterns(cfg.EventsAnnotationMatch)
+	if err != nil {
+		return nil, errors.Wrap(err, "annotation match")
+	}
+	a.match = match
+
+	for _, t := range []struct {
+		name string
+		text string
+		dest **template.Template
+	}{
+		{"title", cfg.EventsAnnotationTitle, &a.title},
+		{"category", cfg.EventsAnnotationCategory, &a.category},
+		{"description", cfg.EventsAnnotationDescription, &a.description},
+	} {
+		tmpl, err := template.New(t.name).Funcs(templateFuncs).Option("missingkey=zero").Parse(t.text)
+		if err != nil {
+			return nil, errors.Wrapf(err, "annotation %s template", t.name)
+		}
+		*t.dest = tmpl
+	}
+
+	window := cfg.EventsAnnotationWindow
+	if window == "" {
+		window = defaults.K8SEventsAnnotationWindow
+	}
+	w, err := time.ParseDuration(window)
+	if err != nil {
+		return nil, errors.Wrap(err, "annotation window")
+	}
+	if w <= 0 {
+		return nil, errors.Errorf("invalid annotation window (%s)", window)
+	}
+	a.window = w
+
+	return a, nil
+}
+
+// add records occurrences of an event, events not matching are ignored
+func (a *annotator) add(event *corev1.Event, n uint64) {
+	if a == nil || n == 0 || !a.matches(event) {
+		return
+	}
+
+	data := annotationData{
+		Cluster:   a.cluster,
+		Namespace: event.Namespace,
+		Name:      event.InvolvedObject.Name,
+		Kind:      event.InvolvedObject.Kind,
+		Type:      event.Type,
+		Reason:    event.Reason,
+		Message:   event.Message,
+		Source:    event.Source.Component,
+		Host:      event.Source.Host,
+		Count:     n,
+	}
+	title, err := render(a.title, data)
+	if err != nil {
+		a.log.Warn().Err(err).Msg("annotation title")
+		return
+	}
+	category, err := render(a.category, data)
+	if err != nil {
+		a.log.Warn().Err(err).Msg("annotation category")
+		return
+	}
+	description, err := render(a.description, data)
+	if err != nil {
+		a.log.Warn().Err(err).Msg("annotation description")
+		return
+	}
+
+	a.aggregate(title, category, description, lastSeen(event), n, time.Now())
+}
+
+// aggregate adds occurrences to the group for the title and category,
+// starting a new group if there is none
+func (a *annotator) aggregate(title, category, description string, seen time.Time, n uint64, now time.Time) {
+	key := category + "\xff" + title
+
+	a.Lock()
+	defer a.Unlock()
+
+	g, ok := a.groups[key]
+	if !ok {
+		a.groups[key] = &annotationGroup{
+			title:       title,
+			category:    category,
+			description: description,
+			start:       seen,
+			stop:        seen,
+			count:       n,
+			expires:     now.Add(a.window),
+		}
+		a.order = append(a.order, key)
+		return
+	}
+
+	g.count += n
+	if seen.Before(g.start) {
+		g.start = seen
+	}
+	if seen.After(g.stop) {
+		g.stop = seen
+	}
+}
+
+// matches returns whether an event matches any of the annotation
+// field patterns, all events match when there are no patterns
+func (a *annotator) matches(event *corev1.Event) bool {
+	if len(a.match) == 0 {
+		return true
+	}
+	for field, res := range a.match {
+		if matchAny(res, filterFields[field](event)) {
+			return true
+		}
+	}
+	return false
+}
+
+// run creates the annotations of expired groups until ctx is done,
+// then creates the annotations still pending
+func (a *annotator) run(ctx context.Context) {
+	if a == nil {
+		return
+	}
+
+	ticker := time.NewTicker(a.window / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			a.create(a.ready(time.Now(), true))
+			return
+		case now := <-ticker.C:
+			a.create(a.ready(now, false))
+		}
+	}
+}
+
+// ready removes and returns the annotations of expired groups (all groups
+// when flushing), annotations over the rate limit are dropped
+func (a *annotator) ready(now time.Time, flush bool) []*apiclient.Annotation {
+	a.Lock()
+	defer a.Unlock()
+
+	// only creations within the last minute count against the limit
+	recent := a.sent[:0]
+	for _, t := range a.sent {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	a.sent = recent
+
+	var annotations []*apiclient.Annotation
+	dropped := 0
+	pending := a.order[:0]
+	for _, key := range a.order {
+		g := a.groups[key]
+		if !flush && now.Before(g.expires) {
+			pending = append(pending, key)
+			continue
+		}
+		delete(a.groups, key)
+		if a.rateLimit > 0 && len(a.sent) >= a.rateLimit {
+			dropped++
+			continue
+		}
+		a.sent = append(a.sent, now)
+		annotations = append(annotations, g.annotation())
+	}
+	a.order = pending
+
+	if dropped > 0 {
+		a.check.IncrementCounterByValue("collect_annotations_dropped", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+		}, uint64(dropped))
+		a.log.Warn().Int("dropped", dropped).Int("limit", a.rateLimit).Msg("annotation rate limit reached")
+	}
+
+	return annotations
+}
+
+// create creates annotations
+func (a *annotator) create(annotations []*apiclient.Annotation) {
+	for _, annotation := range annotations {
+		if err := a.check.CreateAnnotation(annotation); err != nil {
+			a.check.IncrementCounter("collect_api_errors", cgm.Tags{
+				cgm.Tag{Category: "source", Value: release.NAME},
+				cgm.Tag{Category: "request", Value: "annotation"},
+				cgm.Tag{Category: "target", Value: "circonus"},
+			})
+			a.log.Warn().Err(err).Str("title", annotation.Title).Msg("creating annotation")
+			continue
+		}
+		a.check.IncrementCounter("collect_annotations", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+		})
+	}
+}
+
+// annotation returns the annotation of a group, the description
+// notes the number of occurrences when more than one
+func (g *annotationGroup) annotation() *apiclient.Annotation {
+	description := g.description
+	if g.count > 1 {
+		description = strings.TrimSpace(description + " (" + strconv.FormatUint(g.count, 10) + " occurrences)")
+	}
+	stop := g.stop
+	if stop.Before(g.start) {
+		stop = g.start
+	}
+	return &apiclient.Annotation{
+		Title:          g.title,
+		Category:       g.category,
+		Description:    description,
+		Start:          uint(g.start.Unix()),
+		Stop:           uint(stop.Unix()),
+		RelatedMetrics: []string{},
+	}
+}
+
+func render(tmpl *template.Template, data annotationData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
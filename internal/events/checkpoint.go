@@ -0,0 +1,167 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// checkpointKey is the configmap data key of the checkpoint
+	checkpointKey = "checkpoint"
+	// maxCheckpointEvents is the maximum number of events persisted in a
+	// checkpoint, the most recently seen are kept (configmaps are limited to 1MiB)
+	maxCheckpointEvents = 5000
+)
+
+// checkpoint is the persisted state of the event watcher, on restart
+// the watch resumes from the resource version, events last seen before
+// the checkpoint are skipped and the event counts de-duplicate
+// occurrences already submitted
+type checkpoint struct {
+	ResourceVersion string               `json:"resource_version"` // of the last submitted event
+	LastSeen        time.Time            `json:"last_seen"`        // of the last submitted event
+	Events          map[string]seenEvent `json:"events"`           // by event uid
+}
+
+// seenEvent is the occurrence count of an event already handled
+type seenEvent struct {
+	Count    uint64    `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// checkpointStore loads and saves checkpoints
+type checkpointStore interface {
+	Load() (*checkpoint, error) // nil when there is no checkpoint
+	Save(cp *checkpoint) error
+}
+
+// fileStore persists the checkpoint to a file (e.g. on a persistent volume)
+type fileStore struct {
+	path string
+}
+
+func (s *fileStore) Load() (*checkpoint, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "reading checkpoint")
+	}
+	return decodeCheckpoint(data)
+}
+
+func (s *fileStore) Save(cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "encoding checkpoint")
+	}
+	// write and rename so a partially written checkpoint is never loaded
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "creating checkpoint")
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "writing checkpoint")
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "writing checkpoint")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), s.path), "saving checkpoint")
+}
+
+// configMapStore persists the checkpoint to a configmap, so it is
+// available to whichever replica runs the event watcher
+type configMapStore struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	name      string
+}
+
+func (s *configMapStore) Load() (*checkpoint, error) {
+	cm, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(s.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "getting checkpoint configmap")
+	}
+	data, ok := cm.Data[checkpointKey]
+	if !ok || data == "" {
+		return nil, nil
+	}
+	return decodeCheckpoint([]byte(data))
+}
+
+func (s *configMapStore) Save(cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "encoding checkpoint")
+	}
+
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(s.name, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "getting checkpoint configmap")
+		}
+		_, err = configMaps.Create(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: s.name},
+			Data:       map[string]string{checkpointKey: string(data)},
+		})
+		return errors.Wrap(err, "creating checkpoint configmap")
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[checkpointKey] = string(data)
+	_, err = configMaps.Update(cm)
+	return errors.Wrap(err, "updating checkpoint configmap")
+}
+
+func decodeCheckpoint(data []byte) (*checkpoint, error) {
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, errors.Wrap(err, "decoding checkpoint")
+	}
+	if cp.Events == nil {
+		cp.Events = make(map[string]seenEvent)
+	}
+	return &cp, nil
+}
+
+// trim drops all but the max most recently seen events
+func (cp *checkpoint) trim(max int) {
+	if len(cp.Events) <= max {
+		return
+	}
+	uids := make([]string, 0, len(cp.Events))
+	for uid := range cp.Events {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		return cp.Events[uids[i]].LastSeen.After(cp.Events[uids[j]].LastSeen)
+	})
+	for _, uid := range uids[max:] {
+		delete(cp.Events, uid)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package events
+
+import (
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// countEvent increments the event_count counter by n new occurrences of
+// an event, tagged by type, reason, involved object kind, and namespace.
+// The counters are flushed (and reset) with the check's other metrics
+// each interval.
+func (e *Events) countEvent(event *corev1.Event, n uint64) {
+	if n == 0 {
+		return
+	}
+	e.check.IncrementCounterByValue("event_count", counterTags(event), n)
+}
+
+// eventCount returns the number of times an event has occurred, events
+// using the events.k8s.io series report the count in the series
+func eventCount(event *corev1.Event) uint64 {
+	switch {
+	case event.Series != nil && event.Series.Count > 0:
+		return uint64(event.Series.Count)
+	case event.Count > 0:
+		return uint64(event.Count)
+	default:
+		return 1
+	}
+}
+
+// lastSeen returns when an event last occurred
+func lastSeen(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// firstSeen returns when an event first occurred, when it last
+// occurred if that is not known
+func firstSeen(event *corev1.Event) time.Time {
+	switch {
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.CreationTimestamp.IsZero():
+		return event.CreationTimestamp.Time
+	default:
+		return lastSeen(event)
+	}
+}
+
+// counterTags returns the event_count tags for an event, blank
+// values (e.g. namespace of cluster scoped objects) are omitted
+func counterTags(event *corev1.Event) cgm.Tags {
+	tags := cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}
+	for _, t := range []cgm.Tag{
+		{Category: "type", Value: event.Type},
+		{Category: "reason", Value: event.Reason},
+		{Category: "involved_kind", Value: event.InvolvedObject.Kind},
+		{Category: "namespace", Value: event.Namespace},
+	} {
+		if t.Value != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
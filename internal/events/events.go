@@ -10,23 +10,42 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"errors"
+	"time"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/client-go/informers"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 )
 
+// checkpointInterval is how often the checkpoint is saved when it has changed
+const checkpointInterval = 30 * time.Second
+
 type Events struct {
-	config *config.Cluster
-	check  *circonus.Check
-	log    zerolog.Logger
+	config    *config.Cluster
+	check     *circonus.Check
+	log       zerolog.Logger
+	filter    *eventFilter // nil=all events
+	annotator *annotator   // nil=annotations not enabled
+}
+
+func init() {
+	registry.Register("events", func(env registry.Env) (registry.Collector, error) {
+		e, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
 }
 
 func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Events, error) {
@@ -37,11 +56,24 @@ func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (
 		return nil, errors.New("invalid check (nil)")
 	}
 
+	f, err := newEventFilter(cfg.EventsInclude, cfg.EventsExclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "events collector")
+	}
+
 	e := &Events{
 		config: cfg,
 		check:  check,
 		log:    parentLog.With().Str("collector", "events").Logger(),
+		filter: f,
+	}
+
+	a, err := newAnnotator(cfg, check, e.log)
+	if err != nil {
+		return nil, errors.Wrap(err, "events collector")
 	}
+	e.annotator = a
+
 	return e, nil
 }
 
@@ -51,58 +83,152 @@ func (e *Events) ID() string {
 
 func (e *Events) Start(ctx context.Context, tlsConfig *tls.Config) {
 	e.log.Info().Msg("starting watcher")
+	start := time.Now()
 
-	var cfg *rest.Config
-	if c, err := rest.InClusterConfig(); err != nil {
-		if err != rest.ErrNotInCluster {
-			e.log.Error().Err(err).Msg("unable to start event monitor")
-			return
-		}
-		// not in cluster, use supplied customer config for cluster
-		cfg = &rest.Config{}
-		if e.config.BearerToken != "" {
-			cfg.BearerToken = e.config.BearerToken
-		}
-		if e.config.URL != "" {
-			cfg.Host = e.config.URL
-		}
-		if e.config.CAFile != "" {
-			cfg.TLSClientConfig = rest.TLSClientConfig{CAFile: e.config.CAFile}
-		}
-	} else {
-		cfg = c // use in-cluster config
+	clientset, err := k8s.NewClientset(e.config)
+	if err != nil {
+		e.log.Error().Err(err).Msg("unable to start event monitor")
+		return
 	}
 
-	clientset, err := kubernetes.NewForConfig(cfg)
+	store, err := e.checkpointStore(clientset)
 	if err != nil {
-		e.log.Error().Err(err).Msg("initializing client set")
-		return
+		e.log.Error().Err(err).Msg("event checkpoint, events before agent start are skipped")
 	}
+	var cp *checkpoint
+	if store != nil {
+		cp, err = store.Load()
+		if err != nil {
+			e.log.Warn().Err(err).Msg("loading event checkpoint, events before agent start are skipped")
+		} else if cp != nil {
+			e.log.Info().Time("last_seen", cp.LastSeen).Str("resource_version", cp.ResourceVersion).Msg("resuming from event checkpoint")
+		}
+	}
+	t := newTracker(start, cp)
 
-	factory := informers.NewSharedInformerFactory(clientset, 0)
-	informer := factory.Core().V1().Events().Informer()
+	resourceVersion := ""
+	if cp != nil {
+		resourceVersion = cp.ResourceVersion
+	}
+	informer := cache.NewSharedIndexInformer(
+		e.listWatch(clientset, resourceVersion),
+		&corev1.Event{},
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
 	stopper := make(chan struct{})
 	defer close(stopper)
 	defer runtime.HandleCrash()
 
+	// events in the api server cache are replayed as added when the
+	// watcher starts and updated (count incremented) when they re-occur,
+	// the tracker skips old events and already submitted occurrences
+	handle := func(event *corev1.Event) {
+		if !e.filter.match(event) {
+			return
+		}
+		n, ok := t.observe(event)
+		if !ok {
+			return
+		}
+		e.submitEvent(ctx, event)
+		e.countEvent(event, n)
+		e.annotator.add(event, n)
+	}
+
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
-			e.submitEvent(ctx, obj.(*corev1.Event))
+			handle(obj.(*corev1.Event))
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			handle(newObj.(*corev1.Event))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if event, ok := obj.(*corev1.Event); ok {
+				t.forget(event)
+			}
 		},
-		// UpdateFunc: func(oldObj interface{}, newObj interface{}) {
-		// 	e.submitEvent(newObj.(*corev1.Event))
-		// },
 	})
 
 	go informer.Run(stopper)
+	go e.annotator.run(ctx)
 
 	if !cache.WaitForCacheSync(stopper, informer.HasSynced) {
 		e.log.Warn().Msg("timed out waiting for cache to sync")
 		return
 	}
 
-	<-ctx.Done()
-	e.log.Debug().Msg("closing event watcher")
+	if store == nil {
+		<-ctx.Done()
+		e.log.Debug().Msg("closing event watcher")
+		return
+	}
+
+	ticker := time.NewTicker(checkpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.saveCheckpoint(store, t)
+			e.log.Debug().Msg("closing event watcher")
+			return
+		case <-ticker.C:
+			e.saveCheckpoint(store, t)
+		}
+	}
+}
+
+// listWatch lists and watches events in all namespaces. When resuming
+// from a checkpoint the initial list is skipped and the watch starts at
+// the checkpointed resource version. If that has expired the watch fails
+// (410 Gone) and the reflector relists (resource version "0", the api
+// server cache), the tracker skips events seen before the checkpoint.
+func (e *Events) listWatch(clientset *kubernetes.Clientset, resourceVersion string) *cache.ListWatch {
+	events := clientset.CoreV1().Events(metav1.NamespaceAll)
+	resume := resourceVersion != ""
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+			if resume {
+				// the reflector watches from the resource version of the list
+				resume = false
+				return &corev1.EventList{ListMeta: metav1.ListMeta{ResourceVersion: resourceVersion}}, nil
+			}
+			return events.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return events.Watch(options)
+		},
+	}
+}
+
+// checkpointStore returns the configured checkpoint store, nil if none
+func (e *Events) checkpointStore(clientset *kubernetes.Clientset) (checkpointStore, error) {
+	switch {
+	case e.config.EventsCheckpointFile != "":
+		return &fileStore{path: e.config.EventsCheckpointFile}, nil
+	case e.config.EventsCheckpointConfigMap != "":
+		ns, err := k8s.AgentNamespace()
+		if err != nil {
+			return nil, err
+		}
+		return &configMapStore{clientset: clientset, namespace: ns, name: e.config.EventsCheckpointConfigMap}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// saveCheckpoint saves the tracker state if it changed
+func (e *Events) saveCheckpoint(store checkpointStore, t *tracker) {
+	cp := t.checkpoint()
+	if cp == nil {
+		return
+	}
+	if err := store.Save(cp); err != nil {
+		e.log.Warn().Err(err).Msg("saving event checkpoint")
+	}
 }
 
 type abridgedEvent struct {
@@ -5,8 +5,241 @@
 
 package events
 
-import "testing"
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
 
-func Test(t *testing.T) {
-	t.Log("Placeholder...nothing to test currently")
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEventCount(t *testing.T) {
+	tests := []struct {
+		desc   string
+		event  *corev1.Event
+		expect uint64
+	}{
+		{"no count", &corev1.Event{}, 1},
+		{"count", &corev1.Event{Count: 3}, 3},
+		{"series", &corev1.Event{Count: 1, Series: &corev1.EventSeries{Count: 6}}, 6},
+	}
+
+	for _, test := range tests {
+		if n := eventCount(test.event); n != test.expect {
+			t.Errorf("%s: expected %d, got %d", test.desc, test.expect, n)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(uid string, count int32, seen time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{UID: types.UID(uid)},
+			Count:         count,
+			LastTimestamp: metav1.NewTime(seen),
+		}
+	}
+
+	tr := newTracker(start, nil)
+	if n, _ := tr.observe(event("old", 3, start.Add(-time.Minute))); n != 0 {
+		t.Fatalf("expected event before start to be skipped, got %d", n)
+	}
+	if n, _ := tr.observe(event("old", 5, start.Add(time.Minute))); n != 2 {
+		t.Fatalf("expected 2 new occurrences of old event, got %d", n)
+	}
+	if n, _ := tr.observe(event("new", 2, start.Add(time.Minute))); n != 2 {
+		t.Fatalf("expected 2 occurrences of new event, got %d", n)
+	}
+	if n, ok := tr.observe(event("new", 2, start.Add(time.Minute))); n != 0 || ok {
+		t.Fatalf("expected duplicate to be skipped, got %d %v", n, ok)
+	}
+
+	// occurred before start and again after, the count at first sight is the baseline
+	restarted := event("restarted", 500, start.Add(time.Minute))
+	restarted.FirstTimestamp = metav1.NewTime(start.Add(-time.Hour))
+	if n, ok := tr.observe(restarted); n != 0 || !ok {
+		t.Fatalf("expected occurrences before start not to be counted, got %d %v", n, ok)
+	}
+	restarted = event("restarted", 501, start.Add(2*time.Minute))
+	restarted.FirstTimestamp = metav1.NewTime(start.Add(-time.Hour))
+	if n, _ := tr.observe(restarted); n != 1 {
+		t.Fatalf("expected 1 new occurrence after the baseline, got %d", n)
+	}
+
+	cp := tr.checkpoint()
+	if cp == nil || !cp.LastSeen.Equal(start.Add(2*time.Minute)) || len(cp.Events) != 3 {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+	if tr.checkpoint() != nil {
+		t.Fatal("expected no checkpoint when unchanged")
+	}
+
+	// resume from the checkpoint, the cutoff is the checkpoint
+	tr = newTracker(start.Add(time.Hour), cp)
+	if n, _ := tr.observe(event("new", 2, start.Add(time.Minute))); n != 0 {
+		t.Fatalf("expected checkpointed event to be skipped, got %d", n)
+	}
+	if n, _ := tr.observe(event("new", 3, start.Add(2*time.Minute))); n != 1 {
+		t.Fatalf("expected 1 new occurrence after checkpoint, got %d", n)
+	}
+	if n, _ := tr.observe(event("down", 1, start.Add(30*time.Minute))); n != 1 {
+		t.Fatalf("expected event while agent was down to be submitted, got %d", n)
+	}
+}
+
+func TestCheckpointTrim(t *testing.T) {
+	now := time.Now()
+	cp := &checkpoint{Events: map[string]seenEvent{
+		"a": {Count: 1, LastSeen: now.Add(-3 * time.Minute)},
+		"b": {Count: 1, LastSeen: now.Add(-time.Minute)},
+		"c": {Count: 1, LastSeen: now.Add(-2 * time.Minute)},
+	}}
+	cp.trim(2)
+	if _, ok := cp.Events["a"]; ok || len(cp.Events) != 2 {
+		t.Fatalf("expected oldest event trimmed, got %v", cp.Events)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := &fileStore{path: filepath.Join(dir, "checkpoint.json")}
+	if cp, err := s.Load(); err != nil || cp != nil {
+		t.Fatalf("expected no checkpoint, got %v (%v)", cp, err)
+	}
+
+	seen := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.Save(&checkpoint{ResourceVersion: "42", LastSeen: seen, Events: map[string]seenEvent{"a": {Count: 2, LastSeen: seen}}}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.ResourceVersion != "42" || !cp.LastSeen.Equal(seen) || cp.Events["a"].Count != 2 {
+		t.Fatalf("unexpected checkpoint %+v", cp)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	if _, err := newEventFilter("bogus=x", ""); err == nil {
+		t.Fatal("expected error for invalid field")
+	}
+	if _, err := newEventFilter("", "reason"); err == nil {
+		t.Fatal("expected error for missing pattern")
+	}
+	if f, err := newEventFilter("", ""); err != nil || f != nil {
+		t.Fatalf("expected nil filter, got %v (%v)", f, err)
+	}
+
+	f, err := newEventFilter("type=Warning,kind=Pod,kind=Node", "namespace=kube-*,reason=/^(BackOff|Unhealthy){1,2}$/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc   string
+		event  *corev1.Event
+		expect bool
+	}{
+		{"included", &corev1.Event{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}, Type: "Warning", Reason: "FailedScheduling", InvolvedObject: corev1.ObjectReference{Kind: "Pod"}}, true},
+		{"second kind", &corev1.Event{Type: "Warning", Reason: "NodeNotReady", InvolvedObject: corev1.ObjectReference{Kind: "Node"}}, true},
+		{"not warning", &corev1.Event{Type: "Normal", Reason: "Pulled", InvolvedObject: corev1.ObjectReference{Kind: "Pod"}}, false},
+		{"not kind", &corev1.Event{Type: "Warning", Reason: "x", InvolvedObject: corev1.ObjectReference{Kind: "Deployment"}}, false},
+		{"excluded namespace", &corev1.Event{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system"}, Type: "Warning", InvolvedObject: corev1.ObjectReference{Kind: "Pod"}}, false},
+		{"excluded reason", &corev1.Event{Type: "Warning", Reason: "BackOff", InvolvedObject: corev1.ObjectReference{Kind: "Pod"}}, false},
+	}
+
+	for _, test := range tests {
+		if m := f.match(test.event); m != test.expect {
+			t.Errorf("%s: expected %v, got %v", test.desc, test.expect, m)
+		}
+	}
+}
+
+func TestCounterTags(t *testing.T) {
+	event := &corev1.Event{
+		Type:           "Warning",
+		Reason:         "BackOff",
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod"},
+	}
+
+	tags := counterTags(event)
+	found := make(map[string]string)
+	for _, tag := range tags {
+		found[tag.Category] = tag.Value
+	}
+	if found["type"] != "Warning" || found["reason"] != "BackOff" || found["involved_kind"] != "Pod" {
+		t.Fatalf("unexpected tags %v", tags)
+	}
+	if _, ok := found["namespace"]; ok {
+		t.Fatalf("expected no namespace tag for blank namespace, got %v", tags)
+	}
+}
+
+func TestAnnotator(t *testing.T) {
+	cfg := &config.Cluster{
+		Name:                        "prod",
+		EventsAnnotations:           true,
+		EventsAnnotationMatch:       defaults.K8SEventsAnnotationMatch,
+		EventsAnnotationTitle:       defaults.K8SEventsAnnotationTitle,
+		EventsAnnotationCategory:    defaults.K8SEventsAnnotationCategory,
+		EventsAnnotationDescription: defaults.K8SEventsAnnotationDescription,
+		EventsAnnotationWindow:      "1m",
+		EventsAnnotationRateLimit:   1,
+	}
+	a, err := newAnnotator(cfg, &circonus.Check{}, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backoff := func(pod string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "default"},
+			Type:           "Warning",
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod},
+		}
+	}
+
+	a.add(&corev1.Event{Type: "Normal", Reason: "Pulled"}, 1) // not matched
+	a.add(backoff("web-1"), 1)
+	a.add(backoff("web-1"), 2)
+	a.add(backoff("web-2"), 1)
+
+	if list := a.ready(time.Now(), false); len(list) != 0 {
+		t.Fatalf("expected no annotations within the window, got %d", len(list))
+	}
+
+	list := a.ready(time.Now().Add(2*time.Minute), false)
+	if len(list) != 1 {
+		t.Fatalf("expected 1 annotation (rate limited), got %d", len(list))
+	}
+	if list[0].Title != "BackOff Pod default/web-1" || list[0].Category != "kubernetes prod" {
+		t.Fatalf("unexpected annotation %+v", list[0])
+	}
+	if expect := "Warning: Back-off restarting failed container (3 occurrences)"; list[0].Description != expect {
+		t.Fatalf("expected description %q, got %q", expect, list[0].Description)
+	}
+	if len(a.groups) != 0 || len(a.order) != 0 {
+		t.Fatal("expected dropped annotation to be removed")
+	}
+
+	if _, err := newAnnotator(&config.Cluster{EventsAnnotations: true, EventsAnnotationTitle: "{{.Reason"}, &circonus.Check{}, zerolog.Nop()); err == nil {
+		t.Fatal("expected error for invalid template")
+	}
 }
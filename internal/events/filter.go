@@ -0,0 +1,97 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package events
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// filterFields are the event fields filters can match
+var filterFields = map[string]func(*corev1.Event) string{
+	"namespace": func(e *corev1.Event) string { return e.Namespace },
+	"type":      func(e *corev1.Event) string { return e.Type },
+	"reason":    func(e *corev1.Event) string { return e.Reason },
+	"kind":      func(e *corev1.Event) string { return e.InvolvedObject.Kind },
+}
+
+// eventFilter selects the events collected, a nil filter collects all events
+type eventFilter struct {
+	include map[string][]*regexp.Regexp // field patterns, an event must match each field
+	exclude map[string][]*regexp.Regexp // field patterns, an event matching any is dropped
+}
+
+// newEventFilter parses include and exclude settings, comma separated
+// field=pattern items where field is namespace, type, reason, or kind
+// and pattern is a glob or a regular expression enclosed in slashes
+// (e.g. "type=Warning,kind=Pod", "reason=/^(Pulled|Pulling)$/")
+func newEventFilter(include, exclude string) (*eventFilter, error) {
+	if strings.TrimSpace(include) == "" && strings.TrimSpace(exclude) == "" {
+		return nil, nil
+	}
+
+	inc, err := parseFieldPatterns(include)
+	if err != nil {
+		return nil, errors.Wrap(err, "events include")
+	}
+	exc, err := parseFieldPatterns(exclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "events exclude")
+	}
+
+	return &eventFilter{include: inc, exclude: exc}, nil
+}
+
+func parseFieldPatterns(list string) (map[string][]*regexp.Regexp, error) {
+	fields := make(map[string][]*regexp.Regexp)
+	for _, item := range tagrules.Split(list) {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, errors.Errorf("invalid item (%s) field=pattern", item)
+		}
+		field := strings.ToLower(strings.TrimSpace(kv[0]))
+		if _, ok := filterFields[field]; !ok {
+			return nil, errors.Errorf("invalid field (%s) namespace|type|reason|kind", field)
+		}
+		res, err := tagrules.Patterns(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		fields[field] = append(fields[field], res...)
+	}
+	return fields, nil
+}
+
+// match returns whether an event is collected
+func (f *eventFilter) match(event *corev1.Event) bool {
+	if f == nil {
+		return true
+	}
+	for field, res := range f.include {
+		if !matchAny(res, filterFields[field](event)) {
+			return false
+		}
+	}
+	for field, res := range f.exclude {
+		if matchAny(res, filterFields[field](event)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(res []*regexp.Regexp, value string) bool {
+	for _, re := range res {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package events
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// tracker de-duplicates events, the informer replays the events in the
+// api server cache when the watcher starts and an event is updated
+// (count incremented) each time it re-occurs
+type tracker struct {
+	cutoff          time.Time // events last seen before are skipped
+	events          map[string]seenEvent
+	resourceVersion string
+	lastSeen        time.Time
+	dirty           bool // changed since the last checkpoint
+	sync.Mutex
+}
+
+// newTracker returns a tracker skipping events last seen before
+// the checkpoint, or before start when there is no checkpoint
+func newTracker(start time.Time, cp *checkpoint) *tracker {
+	// event timestamps have second granularity
+	t := &tracker{
+		cutoff: start.Truncate(time.Second),
+		events: make(map[string]seenEvent),
+	}
+	if cp != nil {
+		if !cp.LastSeen.IsZero() {
+			t.cutoff = cp.LastSeen
+		}
+		for uid, se := range cp.Events {
+			t.events[uid] = se
+		}
+		t.resourceVersion = cp.ResourceVersion
+		t.lastSeen = cp.LastSeen
+	}
+	return t
+}
+
+// observe records an added or updated event and returns the number of
+// occurrences not already handled, and false when the event should be
+// skipped. The count of an event first seen after it already occurred
+// before the cutoff is the baseline, only later occurrences are counted.
+func (t *tracker) observe(event *corev1.Event) (uint64, bool) {
+	uid := eventUID(event)
+	count := eventCount(event)
+	seen := lastSeen(event)
+
+	t.Lock()
+	defer t.Unlock()
+
+	prev, known := t.events[uid]
+	if known && count <= prev.Count {
+		return 0, false // duplicate or resync, no new occurrences
+	}
+	t.events[uid] = seenEvent{Count: count, LastSeen: seen}
+	t.dirty = true
+
+	if seen.Before(t.cutoff) {
+		// old event, recorded so later occurrences are counted from here
+		return 0, false
+	}
+
+	if !seen.Before(t.lastSeen) {
+		t.lastSeen = seen
+		t.resourceVersion = event.ResourceVersion
+	}
+
+	switch {
+	case known:
+		return count - prev.Count, true
+	case firstSeen(event).Before(t.cutoff):
+		// occurred before the cutoff, which of the occurrences
+		// are new is unknown, count from here
+		return 0, true
+	default:
+		return count, true
+	}
+}
+
+// forget removes a deleted event
+func (t *tracker) forget(event *corev1.Event) {
+	t.Lock()
+	defer t.Unlock()
+	if _, ok := t.events[eventUID(event)]; ok {
+		delete(t.events, eventUID(event))
+		t.dirty = true
+	}
+}
+
+// checkpoint returns the current state, nil if unchanged since the last checkpoint
+func (t *tracker) checkpoint() *checkpoint {
+	t.Lock()
+	defer t.Unlock()
+	if !t.dirty {
+		return nil
+	}
+	t.dirty = false
+
+	cp := &checkpoint{
+		ResourceVersion: t.resourceVersion,
+		LastSeen:        t.lastSeen,
+		Events:          make(map[string]seenEvent, len(t.events)),
+	}
+	for uid, se := range t.events {
+		cp.Events[uid] = se
+	}
+	cp.trim(maxCheckpointEvents)
+	return cp
+}
+
+// eventUID returns the uid of an event, namespace/name if not set
+func eventUID(event *corev1.Event) string {
+	if event.UID != "" {
+		return string(event.UID)
+	}
+	return event.Namespace + "/" + event.Name
+}
@@ -0,0 +1,230 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package filter decides which namespaces and pods metrics are
+// collected for, using namespace include/exclude lists, a pod label
+// selector, and an opt-out annotation on pods and namespaces
+package filter
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resyncPeriod is how often the informer replays the cached namespaces
+const resyncPeriod = 5 * time.Minute
+
+// Filter is the namespace and pod collection filter for a cluster,
+// a nil filter collects everything
+type Filter struct {
+	clientset  *kubernetes.Clientset
+	log        zerolog.Logger
+	include    map[string]bool // blank=all namespaces
+	exclude    map[string]bool
+	selector   labels.Selector // nil=all pods
+	annotation string          // blank=annotation not checked
+	namespaces corelisters.NamespaceLister
+	synced     bool
+	sync.RWMutex
+}
+
+// New returns the filter for a cluster, namespace annotations are
+// not checked until the filter is started
+func New(cfg *config.Cluster, parentLog zerolog.Logger) (*Filter, error) {
+	f, err := newFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f.log = parentLog.With().Str("pkg", "filter").Logger()
+
+	if f.annotation != "" {
+		clientset, err := k8s.NewClientset(cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "filter")
+		}
+		f.clientset = clientset
+	}
+
+	return f, nil
+}
+
+// newFilter parses the filter settings of the cluster configuration
+func newFilter(cfg *config.Cluster) (*Filter, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	f := &Filter{
+		include:    nameSet(cfg.NamespaceInclude),
+		exclude:    nameSet(cfg.NamespaceExclude),
+		annotation: cfg.CollectAnnotation,
+	}
+
+	// the legacy pod label key/value is folded into the selector
+	var sel []string
+	if cfg.PodSelector != "" {
+		sel = append(sel, cfg.PodSelector)
+	}
+	if cfg.PodLabelKey != "" {
+		if cfg.PodLabelVal != "" {
+			sel = append(sel, cfg.PodLabelKey+"="+cfg.PodLabelVal)
+		} else {
+			sel = append(sel, cfg.PodLabelKey)
+		}
+	}
+	if len(sel) > 0 {
+		s, err := labels.Parse(strings.Join(sel, ","))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid pod selector")
+		}
+		f.selector = s
+	}
+
+	return f, nil
+}
+
+// Start watches namespaces (for the opt-out annotation) until ctx is done
+func (f *Filter) Start(ctx context.Context) {
+	if f == nil || f.clientset == nil {
+		return
+	}
+
+	defer runtime.HandleCrash()
+
+	factory := informers.NewSharedInformerFactory(f.clientset, resyncPeriod)
+	nsInformer := factory.Core().V1().Namespaces()
+	informer := nsInformer.Informer()
+
+	f.Lock()
+	f.namespaces = nsInformer.Lister()
+	f.synced = false
+	f.Unlock()
+
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		f.log.Warn().Msg("namespace cache did not sync")
+		return
+	}
+
+	f.Lock()
+	f.synced = true
+	f.Unlock()
+
+	<-ctx.Done()
+
+	f.Lock()
+	f.synced = false
+	f.Unlock()
+}
+
+// Namespace returns whether metrics are collected for a namespace
+func (f *Filter) Namespace(ns string) bool {
+	if f == nil || ns == "" {
+		return true
+	}
+	if len(f.include) > 0 && !f.include[ns] {
+		return false
+	}
+	if f.exclude[ns] {
+		return false
+	}
+	if f.annotation == "" {
+		return true
+	}
+
+	f.RLock()
+	lister := f.namespaces
+	synced := f.synced
+	f.RUnlock()
+
+	if lister == nil || !synced {
+		return true
+	}
+	n, err := lister.Get(ns)
+	if err != nil {
+		return true
+	}
+	return f.collectAnnotation(n.Annotations)
+}
+
+// Pod returns whether metrics are collected for a pod
+func (f *Filter) Pod(ns string, podLabels, annotations map[string]string) bool {
+	if f == nil {
+		return true
+	}
+	if !f.Namespace(ns) {
+		return false
+	}
+	if f.selector != nil && !f.selector.Matches(labels.Set(podLabels)) {
+		return false
+	}
+	return f.collectAnnotation(annotations)
+}
+
+// PodLookup returns the labels and annotations of a pod, false if the pod is unknown
+type PodLookup func(ns, name string) (podLabels, annotations map[string]string, ok bool)
+
+// Series returns a filter for prometheus series using the namespace and
+// pod (or pod_name) labels of the series, pods which cannot be looked up
+// are filtered by namespace only. A nil filter returns nil (all series).
+func (f *Filter) Series(lookup PodLookup) func(name string, labels map[string]string) bool {
+	if f == nil {
+		return nil
+	}
+	return func(_ string, seriesLabels map[string]string) bool {
+		ns := seriesLabels["namespace"]
+		if ns == "" {
+			return true
+		}
+		pod := seriesLabels["pod"]
+		if pod == "" {
+			pod = seriesLabels["pod_name"]
+		}
+		if pod == "" || lookup == nil {
+			return f.Namespace(ns)
+		}
+		podLabels, annotations, ok := lookup(ns, pod)
+		if !ok {
+			return f.Namespace(ns)
+		}
+		return f.Pod(ns, podLabels, annotations)
+	}
+}
+
+// collectAnnotation returns false when the opt-out annotation is "false"
+func (f *Filter) collectAnnotation(annotations map[string]string) bool {
+	if f.annotation == "" {
+		return true
+	}
+	if v, ok := annotations[f.annotation]; ok && strings.EqualFold(strings.TrimSpace(v), "false") {
+		return false
+	}
+	return true
+}
+
+// nameSet parses a comma separated list of names
+func nameSet(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestPod(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Cluster
+		ns          string
+		labels      map[string]string
+		annotations map[string]string
+		want        bool
+	}{
+		{"no filter", config.Cluster{}, "default", nil, nil, true},
+		{"ns included", config.Cluster{NamespaceInclude: "a, b"}, "b", nil, nil, true},
+		{"ns not included", config.Cluster{NamespaceInclude: "a,b"}, "c", nil, nil, false},
+		{"ns excluded", config.Cluster{NamespaceExclude: "kube-system"}, "kube-system", nil, nil, false},
+		{"selector match", config.Cluster{PodSelector: "app in (web,api),tier!=cache"}, "default", map[string]string{"app": "web"}, nil, true},
+		{"selector no match", config.Cluster{PodSelector: "app in (web,api),tier!=cache"}, "default", map[string]string{"app": "web", "tier": "cache"}, nil, false},
+		{"legacy key", config.Cluster{PodLabelKey: "collect"}, "default", map[string]string{"app": "web"}, nil, false},
+		{"legacy key val", config.Cluster{PodLabelKey: "collect", PodLabelVal: "yes"}, "default", map[string]string{"collect": "yes"}, nil, true},
+		{"annotation opt-out", config.Cluster{CollectAnnotation: "circonus.com/collect"}, "default", nil, map[string]string{"circonus.com/collect": "False"}, false},
+		{"annotation opt-in", config.Cluster{CollectAnnotation: "circonus.com/collect"}, "default", nil, map[string]string{"circonus.com/collect": "true"}, true},
+		{"annotation disabled", config.Cluster{}, "default", nil, map[string]string{"circonus.com/collect": "false"}, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newFilter(&tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			if got := f.Pod(tt.ns, tt.labels, tt.annotations); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	t.Log("invalid selector")
+	{
+		if _, err := newFilter(&config.Cluster{PodSelector: "app in (web"}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("nil filter")
+	{
+		var f *Filter
+		if !f.Pod("default", nil, nil) {
+			t.Fatal("expected true")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// NewClientset returns a client-go clientset, using the in-cluster
+// configuration when available, otherwise the cluster configuration.
+func NewClientset(cfg *config.Cluster) (*kubernetes.Clientset, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	var restCfg *rest.Config
+	if c, err := rest.InClusterConfig(); err != nil {
+		if err != rest.ErrNotInCluster {
+			return nil, errors.Wrap(err, "in cluster config")
+		}
+		// not in cluster, use supplied customer config for cluster
+		restCfg = &rest.Config{}
+		if cfg.BearerToken != "" {
+			restCfg.BearerToken = cfg.BearerToken
+		}
+		if cfg.URL != "" {
+			restCfg.Host = cfg.URL
+		}
+		if cfg.CAFile != "" {
+			restCfg.TLSClientConfig = rest.TLSClientConfig{CAFile: cfg.CAFile}
+		}
+	} else {
+		restCfg = c // use in-cluster config
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing client set")
+	}
+
+	return clientset, nil
+}
+
+// AgentNamespace returns the namespace the agent is deployed in (in-cluster only)
+func AgentNamespace() (string, error) {
+	data, err := ioutil.ReadFile(defaults.K8SNamespaceFile)
+	if err != nil {
+		return "", errors.Wrap(err, "agent namespace (set explicitly when not running in-cluster)")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,29 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+type Endpoints struct {
+	Metadata ServiceMetadata  `json:"metadata"`
+	Subsets  []EndpointSubset `json:"subsets"`
+}
+type EndpointSubset struct {
+	Addresses []EndpointAddress `json:"addresses"` // ready addresses
+	Ports     []EndpointPort    `json:"ports"`
+}
+type EndpointAddress struct {
+	IP        string           `json:"ip"`
+	TargetRef *ObjectReference `json:"targetRef"`
+}
+type ObjectReference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+type EndpointPort struct {
+	Name     string `json:"name"` // matches the service port name
+	Port     uint   `json:"port"`
+	Protocol string `json:"protocol"`
+}
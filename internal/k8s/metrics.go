@@ -0,0 +1,53 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package k8s
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resource metrics api (metrics.k8s.io/v1beta1)
+
+type NodeMetricsList struct {
+	Items []NodeMetrics `json:"items"`
+}
+type NodeMetrics struct {
+	Metadata  MetricsMetadata              `json:"metadata"`
+	Timestamp time.Time                    `json:"timestamp"`
+	Window    string                       `json:"window"`
+	Usage     map[string]resource.Quantity `json:"usage"`
+}
+type PodMetricsList struct {
+	Items []PodMetrics `json:"items"`
+}
+type PodMetrics struct {
+	Metadata   MetricsMetadata    `json:"metadata"`
+	Timestamp  time.Time          `json:"timestamp"`
+	Window     string             `json:"window"`
+	Containers []ContainerMetrics `json:"containers"`
+}
+type ContainerMetrics struct {
+	Name  string                       `json:"name"`
+	Usage map[string]resource.Quantity `json:"usage"`
+}
+type MetricsMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// APIService is the registration of an aggregated api (apiregistration.k8s.io/v1)
+type APIService struct {
+	Spec APIServiceSpec `json:"spec"`
+}
+type APIServiceSpec struct {
+	Service *APIServiceReference `json:"service"` // nil=served locally
+}
+type APIServiceReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
@@ -5,15 +5,29 @@
 
 package k8s
 
+import "time"
+
 type NodeList struct {
 	Items []Node `json:"items"`
 }
 
 type Node struct {
 	Metadata NodeMetadata `json:"metadata"`
+	Spec     NodeSpec     `json:"spec"`
 	Status   NodeStatus   `json:"status"`
 }
 
+type NodeSpec struct {
+	Unschedulable bool        `json:"unschedulable"` // cordoned
+	Taints        []NodeTaint `json:"taints"`
+}
+
+type NodeTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Effect string `json:"effect"` // NoSchedule, PreferNoSchedule, NoExecute
+}
+
 type NodeMetadata struct {
 	Name     string            `json:"name"`
 	SelfLink string            `json:"selfLink"`
@@ -21,23 +35,48 @@ type NodeMetadata struct {
 }
 
 type NodeStatus struct {
-	Conditions  []NodeCondition `json:"conditions"`
-	NodeInfo    NodeInfo        `json:"nodeInfo"`
-	Capacity    NodeSizes       `json:"capacity"`
-	Allocatable NodeSizes       `json:"allocatable"`
+	Conditions      []NodeCondition     `json:"conditions"`
+	NodeInfo        NodeInfo            `json:"nodeInfo"`
+	Capacity        NodeSizes           `json:"capacity"`
+	Allocatable     NodeSizes           `json:"allocatable"`
+	Addresses       []NodeAddress       `json:"addresses"`
+	DaemonEndpoints NodeDaemonEndpoints `json:"daemonEndpoints"`
+}
+
+type NodeAddress struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+}
+
+type NodeDaemonEndpoints struct {
+	KubeletEndpoint DaemonEndpoint `json:"kubeletEndpoint"`
 }
 
-type NodeSizes struct {
-	CPU              string `json:"cpu"`
-	Memory           string `json:"memory"`
-	Pods             string `json:"pods"`
-	EphemeralStorage string `json:"ephemeral-storage"`
+type DaemonEndpoint struct {
+	Port int `json:"Port"`
 }
 
+// NodeSizes are resource quantities by resource name (e.g. cpu, memory,
+// pods, ephemeral-storage, hugepages-2Mi, and extended resources)
+type NodeSizes map[string]string
+
 type NodeCondition struct {
-	Type    string `json:"type"`
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Type               string    `json:"type"`
+	Status             string    `json:"status"` // True, False, Unknown
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message"`
+	LastHeartbeatTime  time.Time `json:"lastHeartbeatTime"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// Ready returns the status of the node Ready condition, blank if the node has none
+func (n *Node) Ready() string {
+	for _, cond := range n.Status.Conditions {
+		if cond.Type == "Ready" {
+			return cond.Status
+		}
+	}
+	return ""
 }
 
 type NodeInfo struct {
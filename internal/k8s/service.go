@@ -5,6 +5,11 @@
 
 package k8s
 
+import (
+	"encoding/json"
+	"strconv"
+)
+
 type ServiceList struct {
 	Items []*Service `json:"items"`
 }
@@ -21,8 +26,26 @@ type ServiceSpec struct {
 	Ports []ServicePort `json:"ports"`
 }
 type ServicePort struct {
-	Name       string `json:"name"`
-	Protocol   string `json:"protocol"`
-	Port       uint   `json:"port"`
-	TargetPort string `json:"targetPort"`
+	Name       string      `json:"name"`
+	Protocol   string      `json:"protocol"`
+	Port       uint        `json:"port"`
+	TargetPort IntOrString `json:"targetPort"`
+}
+
+// IntOrString is a port number or name (e.g. service targetPort)
+type IntOrString string
+
+// UnmarshalJSON accepts a json number or string
+func (v *IntOrString) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*v = IntOrString(strconv.Itoa(n))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*v = IntOrString(s)
+	return nil
 }
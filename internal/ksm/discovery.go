@@ -0,0 +1,364 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package ksm
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+)
+
+// discovery is how kube-state-metrics services are found
+type discovery struct {
+	namespace     string // blank=all namespaces
+	service       string // service name, blank=any
+	selector      string // service label selector, blank=none
+	metricsPort   string // service port name or number
+	telemetryPort string // service port name or number, blank=disabled
+}
+
+// newDiscovery returns the discovery settings from the cluster configuration,
+// collector options (namespace, service, selector, metrics_port, and
+// telemetry_port) override the cluster settings
+func newDiscovery(cfg *config.Cluster, options map[string]string) discovery {
+	d := discovery{
+		namespace:     cfg.KSMNamespace,
+		service:       cfg.KSMService,
+		selector:      cfg.KSMSelector,
+		metricsPort:   cfg.KSMMetricsPort,
+		telemetryPort: cfg.KSMTelemetryPort,
+	}
+	for k, v := range options {
+		switch k {
+		case "namespace":
+			d.namespace = v
+		case "service":
+			d.service = v
+		case "selector":
+			d.selector = v
+		case "metrics_port":
+			d.metricsPort = v
+		case "telemetry_port":
+			d.telemetryPort = v
+		}
+	}
+	return d
+}
+
+func (d discovery) String() string {
+	return fmt.Sprintf("namespace=%q service=%q selector=%q", d.namespace, d.service, d.selector)
+}
+
+// target is a kube-state-metrics instance to scrape
+type target struct {
+	shard        string // pod (or service when endpoints are not available) name
+	metricsURL   string
+	telemetryURL string // blank=no telemetry port
+}
+
+// discover returns the kube-state-metrics instances to scrape, each ready
+// endpoint of the matching services, or the service itself when it has
+// no pod endpoints
+func (ksm *KSM) discover(tlsConfig *tls.Config) ([]target, error) {
+	d := ksm.discovery
+	if d.service == "" && d.selector == "" {
+		return nil, errors.New("invalid kube-state-metrics discovery, service name or selector required")
+	}
+	if d.metricsPort == "" {
+		return nil, errors.New("invalid kube-state-metrics discovery, metrics port required")
+	}
+
+	svcURL := ksm.config.URL + "/api/v1/services"
+	if d.namespace != "" {
+		svcURL = ksm.config.URL + "/api/v1/namespaces/" + d.namespace + "/services"
+	}
+	u, err := url.Parse(svcURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	if d.service != "" {
+		q.Set("fieldSelector", "metadata.name="+d.service)
+	}
+	if d.selector != "" {
+		q.Set("labelSelector", d.selector)
+	}
+	u.RawQuery = q.Encode()
+
+	var services k8s.ServiceList
+	if err := ksm.apiGet(tlsConfig, "kube-state-metrics_service", u.String(), &services); err != nil {
+		return nil, errors.Wrap(err, "service list")
+	}
+	if len(services.Items) == 0 {
+		return nil, errors.Errorf("no kube-state-metrics service found (%s)", d)
+	}
+
+	var targets []target
+	for _, svc := range services.Items {
+		metricsPort := findPort(svc.Spec.Ports, d.metricsPort)
+		if metricsPort == nil {
+			ksm.log.Warn().Str("service", svc.Metadata.Namespace+"/"+svc.Metadata.Name).Str("port", d.metricsPort).Msg("metrics port not found, skipping")
+			continue
+		}
+		telemetryPort := findPort(svc.Spec.Ports, d.telemetryPort)
+
+		epURL := ksm.config.URL + "/api/v1/namespaces/" + svc.Metadata.Namespace + "/endpoints/" + svc.Metadata.Name
+		var eps k8s.Endpoints
+		if err := ksm.apiGet(tlsConfig, "kube-state-metrics_endpoints", epURL, &eps); err != nil {
+			ksm.log.Warn().Err(err).Str("service", svc.Metadata.Namespace+"/"+svc.Metadata.Name).Msg("endpoints, using service proxy")
+		}
+
+		svcTargets := endpointTargets(ksm.config.URL, &eps, metricsPort, telemetryPort)
+		if len(svcTargets) == 0 {
+			svcTargets = append(svcTargets, serviceTarget(ksm.config.URL, svc, metricsPort, telemetryPort))
+		}
+		targets = append(targets, svcTargets...)
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.Errorf("no kube-state-metrics service with metrics port %q found (%s)", d.metricsPort, d)
+	}
+
+	return targets, nil
+}
+
+// findPort returns the service port matching a port name or number, nil if not found
+func findPort(ports []k8s.ServicePort, spec string) *k8s.ServicePort {
+	if spec == "" {
+		return nil
+	}
+	for i := range ports {
+		if ports[i].Name == spec || strconv.FormatUint(uint64(ports[i].Port), 10) == spec {
+			return &ports[i]
+		}
+	}
+	return nil
+}
+
+// endpointPort returns the endpoint port of a service port, endpoint ports
+// carry the service port name (unnamed when the service has a single port)
+func endpointPort(ports []k8s.EndpointPort, svcPort *k8s.ServicePort) *k8s.EndpointPort {
+	if svcPort == nil {
+		return nil
+	}
+	for i := range ports {
+		if ports[i].Name == svcPort.Name {
+			return &ports[i]
+		}
+	}
+	return nil
+}
+
+// endpointTargets returns a target for each ready pod endpoint, scraped
+// through the api server pod proxy
+func endpointTargets(baseURL string, eps *k8s.Endpoints, metricsPort, telemetryPort *k8s.ServicePort) []target {
+	var targets []target
+	seen := make(map[string]bool)
+	for _, subset := range eps.Subsets {
+		mp := endpointPort(subset.Ports, metricsPort)
+		if mp == nil {
+			continue
+		}
+		tp := endpointPort(subset.Ports, telemetryPort)
+		for _, addr := range subset.Addresses {
+			if addr.TargetRef == nil || addr.TargetRef.Kind != "Pod" || seen[addr.TargetRef.Name] {
+				continue
+			}
+			seen[addr.TargetRef.Name] = true
+			ns := addr.TargetRef.Namespace
+			if ns == "" {
+				ns = eps.Metadata.Namespace
+			}
+			podURL := baseURL + "/api/v1/namespaces/" + ns + "/pods/" + addr.TargetRef.Name + ":"
+			t := target{
+				shard:      addr.TargetRef.Name,
+				metricsURL: podURL + strconv.FormatUint(uint64(mp.Port), 10) + "/proxy/metrics",
+			}
+			if tp != nil {
+				t.telemetryURL = podURL + strconv.FormatUint(uint64(tp.Port), 10) + "/proxy/metrics"
+			}
+			targets = append(targets, t)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].shard < targets[j].shard })
+	return targets
+}
+
+// serviceTarget returns a target scraped through the api server service proxy
+func serviceTarget(baseURL string, svc *k8s.Service, metricsPort, telemetryPort *k8s.ServicePort) target {
+	svcURL := baseURL + "/api/v1/namespaces/" + svc.Metadata.Namespace + "/services/" + svc.Metadata.Name + ":"
+	t := target{
+		shard:      svc.Metadata.Name,
+		metricsURL: svcURL + portRef(metricsPort) + "/proxy/metrics",
+	}
+	if telemetryPort != nil {
+		t.telemetryURL = svcURL + portRef(telemetryPort) + "/proxy/metrics"
+	}
+	return t
+}
+
+// portRef returns the service port name, or number if unnamed
+func portRef(p *k8s.ServicePort) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return strconv.FormatUint(uint64(p.Port), 10)
+}
+
+// apiGet decodes the json response of an api server request
+func (ksm *KSM) apiGet(tlsConfig *tls.Config, request, reqURL string, v interface{}) error {
+	client, err := k8s.NewAPIClient(tlsConfig, ksm.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, request+" cli")
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, reqURL)
+	if err != nil {
+		return errors.Wrap(err, request+" req")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "target", Value: "api-server"},
+		})
+		return err
+	}
+	defer resp.Body.Close()
+	ksm.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "target", Value: "api-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "target", Value: "api-server"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			ksm.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
+			return err
+		}
+		ksm.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.New("error response from api server")
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// seriesOwners de-duplicates series present on several kube-state-metrics
+// instances (e.g. replicas, or series not sharded). A series is emitted by
+// one instance per collection, the instance which emitted it in the previous
+// collection keeps it so the instance tag of the series is stable. Series
+// are tracked by a hash of their name and labels to bound memory on large
+// payloads.
+type seriesOwners struct {
+	owners  map[uint64]string // series hash -> instance which emitted it last collection
+	claimed map[uint64]string // series hash -> instance emitting it this collection
+	active  map[string]bool   // instances scraped (without error) this collection
+	sync.Mutex
+}
+
+func newSeriesOwners() *seriesOwners {
+	return &seriesOwners{
+		owners:  make(map[uint64]string),
+		claimed: make(map[uint64]string),
+		active:  make(map[string]bool),
+	}
+}
+
+// begin starts a collection of the instances
+func (s *seriesOwners) begin(instances []string) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.claimed = make(map[uint64]string, len(s.owners))
+	s.active = make(map[string]bool, len(instances))
+	for _, i := range instances {
+		s.active[i] = true
+	}
+}
+
+// fail marks an instance whose scrape errored as inactive, so the
+// other instances emit the series it owned for the rest of the collection
+func (s *seriesOwners) fail(instance string) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.active, instance)
+}
+
+// claim returns true if instance should emit a series
+func (s *seriesOwners) claim(instance, name string, labels map[string]string) bool {
+	key := seriesKey(name, labels)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.claimed[key]; ok {
+		return false
+	}
+	if owner, ok := s.owners[key]; ok && owner != instance && s.active[owner] {
+		return false // previous owner is still being scraped
+	}
+	s.claimed[key] = instance
+	return true
+}
+
+// end finishes a collection, series not emitted are released
+func (s *seriesOwners) end() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.owners = s.claimed
+	s.claimed = make(map[uint64]string)
+}
+
+// seriesKey identifies a series by a hash of its name and labels
+func seriesKey(name string, labels map[string]string) uint64 {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	for _, k := range keys {
+		_, _ = h.Write([]byte{0xff})
+		_, _ = h.Write([]byte(k))
+		_, _ = h.Write([]byte{'='})
+		_, _ = h.Write([]byte(labels[k]))
+	}
+	return h.Sum64()
+}
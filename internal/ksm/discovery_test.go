@@ -0,0 +1,162 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package ksm
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+func TestNewDiscovery(t *testing.T) {
+	cfg := &config.Cluster{
+		KSMNamespace:     "monitoring",
+		KSMService:       "kube-state-metrics",
+		KSMMetricsPort:   "http-metrics",
+		KSMTelemetryPort: "telemetry",
+	}
+
+	d := newDiscovery(cfg, nil)
+	if d.namespace != "monitoring" || d.service != "kube-state-metrics" || d.metricsPort != "http-metrics" {
+		t.Fatalf("unexpected discovery from config %+v", d)
+	}
+
+	d = newDiscovery(cfg, map[string]string{"service": "", "selector": "app=ksm", "metrics_port": "8080"})
+	if d.service != "" || d.selector != "app=ksm" || d.metricsPort != "8080" || d.telemetryPort != "telemetry" {
+		t.Fatalf("unexpected discovery from options %+v", d)
+	}
+}
+
+func TestFindPort(t *testing.T) {
+	ports := []k8s.ServicePort{
+		{Name: "http-metrics", Port: 8080},
+		{Name: "telemetry", Port: 8081},
+	}
+
+	tests := []struct {
+		spec string
+		port uint
+	}{
+		{"http-metrics", 8080},
+		{"8081", 8081},
+		{"9000", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		p := findPort(ports, test.spec)
+		switch {
+		case test.port == 0 && p != nil:
+			t.Errorf("%q expected nil, got %d", test.spec, p.Port)
+		case test.port != 0 && (p == nil || p.Port != test.port):
+			t.Errorf("%q expected %d, got %v", test.spec, test.port, p)
+		}
+	}
+}
+
+func TestEndpointTargets(t *testing.T) {
+	mp := &k8s.ServicePort{Name: "http-metrics", Port: 8080}
+	tp := &k8s.ServicePort{Name: "telemetry", Port: 8081}
+
+	eps := &k8s.Endpoints{
+		Metadata: k8s.ServiceMetadata{Name: "kube-state-metrics", Namespace: "monitoring"},
+		Subsets: []k8s.EndpointSubset{
+			{
+				Addresses: []k8s.EndpointAddress{
+					{IP: "10.0.0.2", TargetRef: &k8s.ObjectReference{Kind: "Pod", Name: "ksm-1"}},
+					{IP: "10.0.0.1", TargetRef: &k8s.ObjectReference{Kind: "Pod", Namespace: "monitoring", Name: "ksm-0"}},
+					{IP: "10.0.0.3"},
+				},
+				Ports: []k8s.EndpointPort{
+					{Name: "http-metrics", Port: 8080},
+					{Name: "telemetry", Port: 8081},
+				},
+			},
+		},
+	}
+
+	targets := endpointTargets("https://api", eps, mp, tp)
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].shard != "ksm-0" {
+		t.Fatalf("expected targets sorted by shard, got %s first", targets[0].shard)
+	}
+	expected := "https://api/api/v1/namespaces/monitoring/pods/ksm-1:8080/proxy/metrics"
+	if targets[1].metricsURL != expected {
+		t.Fatalf("expected %s, got %s", expected, targets[1].metricsURL)
+	}
+	expected = "https://api/api/v1/namespaces/monitoring/pods/ksm-1:8081/proxy/metrics"
+	if targets[1].telemetryURL != expected {
+		t.Fatalf("expected %s, got %s", expected, targets[1].telemetryURL)
+	}
+
+	if targets := endpointTargets("https://api", eps, mp, nil); targets[0].telemetryURL != "" {
+		t.Fatalf("expected no telemetry url, got %s", targets[0].telemetryURL)
+	}
+}
+
+func TestSeriesOwners(t *testing.T) {
+	s := newSeriesOwners()
+	labels := map[string]string{"namespace": "x", "pod": "a"}
+
+	s.begin([]string{"ksm-0", "ksm-1"})
+	if !s.claim("ksm-1", "kube_pod_info", labels) {
+		t.Fatal("expected first claim to succeed")
+	}
+	if s.claim("ksm-0", "kube_pod_info", map[string]string{"pod": "a", "namespace": "x"}) {
+		t.Fatal("expected duplicate series to be rejected")
+	}
+	s.end()
+
+	// previous owner keeps the series while it is scraped
+	s.begin([]string{"ksm-0", "ksm-1"})
+	if s.claim("ksm-0", "kube_pod_info", labels) {
+		t.Fatal("expected series to stay with previous owner")
+	}
+	if !s.claim("ksm-1", "kube_pod_info", labels) {
+		t.Fatal("expected previous owner claim to succeed")
+	}
+	s.end()
+
+	// owner no longer scraped, another instance takes over
+	s.begin([]string{"ksm-0"})
+	if !s.claim("ksm-0", "kube_pod_info", labels) {
+		t.Fatal("expected claim to succeed when owner is not scraped")
+	}
+	s.end()
+
+	// owner scrape failed, another instance takes over this collection
+	s.begin([]string{"ksm-0", "ksm-1"})
+	s.fail("ksm-0")
+	if !s.claim("ksm-1", "kube_pod_info", labels) {
+		t.Fatal("expected claim to succeed when owner scrape failed")
+	}
+	s.end()
+
+	t.Log("nil owners")
+	{
+		var s *seriesOwners
+		s.fail("ksm-0")
+	}
+}
+
+func TestSeriesKey(t *testing.T) {
+	a := seriesKey("kube_pod_info", map[string]string{"namespace": "x", "pod": "a"})
+	if b := seriesKey("kube_pod_info", map[string]string{"pod": "a", "namespace": "x"}); a != b {
+		t.Fatal("expected same key regardless of label order")
+	}
+	if b := seriesKey("kube_pod_info", map[string]string{"namespace": "x", "pod": "b"}); a == b {
+		t.Fatal("expected different key for different label value")
+	}
+	if b := seriesKey("kube_pod_info", map[string]string{"namespace": "x=pod", "": "a"}); a == b {
+		t.Fatal("expected different key for different label boundaries")
+	}
+	if b := seriesKey("kube_pod_status", map[string]string{"namespace": "x", "pod": "a"}); a == b {
+		t.Fatal("expected different key for different name")
+	}
+}
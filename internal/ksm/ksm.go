@@ -9,11 +9,10 @@ package ksm
 import (
 	"context"
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 	"sync"
 	"time"
 
@@ -21,9 +20,14 @@ import (
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -34,6 +38,12 @@ type KSM struct {
 	log          zerolog.Logger
 	apiTimelimit time.Duration
 	running      bool
+	filter       *filter.Filter  // nil=all namespaces and pods
+	pods         *podcache.Cache // nil=filter pods by namespace only
+	tags         *tagrules.Rules // nil=all labels become tags
+	rates        *rates.Store    // nil=no counter rates or deltas
+	discovery    discovery
+	series       *seriesOwners // de-duplicates series across instances
 	sync.Mutex
 	ts *time.Time
 }
@@ -44,6 +54,21 @@ type KSM struct {
 // combine selfLink with ':http-metrics/proxy/metrics' for metrics
 // combine selfLink with ':telemetry/proxy/metrics' for ksm telemetry
 
+func init() {
+	registry.Register("kube-state-metrics", func(env registry.Env) (registry.Collector, error) {
+		ksm, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		ksm.filter = env.Filter
+		ksm.pods = env.ClusterPods
+		ksm.tags = env.Tags
+		ksm.rates = env.Rates
+		ksm.discovery = newDiscovery(env.Config, env.Options)
+		return ksm, nil
+	})
+}
+
 func New(cfg *config.Cluster, parentLogger zerolog.Logger, check *circonus.Check) (*KSM, error) {
 	if cfg == nil {
 		return nil, errors.New("invalid cluster config (nil)")
@@ -53,9 +78,11 @@ func New(cfg *config.Cluster, parentLogger zerolog.Logger, check *circonus.Check
 	}
 
 	ksm := &KSM{
-		config: cfg,
-		check:  check,
-		log:    parentLogger.With().Str("collector", "kube-state-metrics").Logger(),
+		config:    cfg,
+		check:     check,
+		log:       parentLogger.With().Str("collector", "kube-state-metrics").Logger(),
+		discovery: newDiscovery(cfg, nil),
+		series:    newSeriesOwners(),
 	}
 
 	if cfg.APITimelimit != "" {
@@ -104,60 +131,88 @@ func (ksm *KSM) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 	}()
 
 	collectStart := time.Now()
-	svc, err := ksm.getServiceDefinition(tlsConfig)
+	targets, err := ksm.discover(tlsConfig)
 	if err != nil {
-		ksm.log.Error().Err(err).Msg("service definition")
+		ksm.log.Error().Err(err).Msg("discovery")
 		ksm.Lock()
 		ksm.running = false
 		ksm.Unlock()
 		return
 	}
-	if svc == nil {
-		ksm.log.Error().Msg("invalid service definition (nil)")
-		ksm.Lock()
-		ksm.running = false
-		ksm.Unlock()
-		return
+
+	// with more than one instance (sharded or replicas) series are tagged
+	// with the instance and series on several instances are emitted once
+	var dedup *seriesOwners
+	if len(targets) > 1 {
+		dedup = ksm.series
+		instances := make([]string, 0, len(targets))
+		for _, t := range targets {
+			instances = append(instances, t.shard)
+		}
+		dedup.begin(instances)
 	}
 
-	metricPath := "/proxy/metrics"
-	metricPortName := ""
-	telemetryPortName := ""
-	for _, p := range svc.Spec.Ports {
-		switch p.Name {
-		case "http-metrics":
-			metricPortName = p.Name
-		case "telemetry":
-			telemetryPortName = p.Name
+	// request the metrics of every instance before parsing any of them,
+	// an instance which cannot be scraped is marked failed so it does
+	// not hold on to the series it owned while the others are parsed
+	bodies := make([]io.ReadCloser, len(targets))
+	{
+		var wg sync.WaitGroup
+		for i, t := range targets {
+			wg.Add(1)
+			go func(i int, t target) {
+				defer wg.Done()
+				body, err := ksm.requestMetrics(tlsConfig, t.metricsURL)
+				if err != nil {
+					ksm.log.Error().Err(err).Str("url", t.metricsURL).Msg("http-metrics")
+					dedup.fail(t.shard)
+					return
+				}
+				bodies[i] = body
+			}(i, t)
 		}
+		wg.Wait()
 	}
 
 	var wg sync.WaitGroup
 
-	if metricPortName != "" {
-		wg.Add(1)
-		go func() {
-			metricURL := ksm.config.URL + svc.Metadata.SelfLink + ":" + metricPortName + metricPath
-			if err := ksm.metrics(ctx, tlsConfig, metricURL); err != nil {
-				ksm.log.Error().Err(err).Str("url", metricURL).Msg("http-metrics")
-			}
-			wg.Done()
-		}()
-	}
+	for i, t := range targets {
+		t := t
+		body := bodies[i]
+		var shardTags []string
+		if len(targets) > 1 {
+			shardTags = append(shardTags, "ksm_shard:"+t.shard)
+		}
 
-	if telemetryPortName != "" {
-		wg.Add(1)
-		go func() {
-			telemetryURL := ksm.config.URL + svc.Metadata.SelfLink + ":" + telemetryPortName + metricPath
-			if err := ksm.telemetry(ctx, tlsConfig, telemetryURL); err != nil {
-				ksm.log.Error().Err(err).Str("url", telemetryURL).Msg("telemetry")
-			}
-			wg.Done()
-		}()
+		if body != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer body.Close()
+				if err := ksm.metrics(ctx, body, t.shard, shardTags, dedup); err != nil {
+					ksm.log.Error().Err(err).Str("url", t.metricsURL).Msg("http-metrics")
+					dedup.fail(t.shard)
+				}
+			}()
+		}
+
+		if t.telemetryURL != "" {
+			wg.Add(1)
+			go func() {
+				if err := ksm.telemetry(ctx, tlsConfig, t.telemetryURL, shardTags); err != nil {
+					ksm.log.Error().Err(err).Str("url", t.telemetryURL).Msg("telemetry")
+				}
+				wg.Done()
+			}()
+		}
 	}
 
 	wg.Wait()
 
+	if dedup != nil {
+		dedup.end()
+	}
+
 	ksm.check.AddHistSample("collect_latency", cgm.Tags{
 		cgm.Tag{Category: "source", Value: release.NAME},
 		cgm.Tag{Category: "op", Value: "collect_kube-state-metrics"},
@@ -169,95 +224,45 @@ func (ksm *KSM) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 	ksm.Unlock()
 }
 
-func (ksm *KSM) getServiceDefinition(tlsConfig *tls.Config) (*k8s.Service, error) {
-	u, err := url.Parse(ksm.config.URL + "/api/v1/services")
-	if err != nil {
-		return nil, err
-	}
-
-	q := u.Query()
-	q.Set("fieldSelector", "metadata.name=kube-state-metrics")
-	u.RawQuery = q.Encode()
-
-	client, err := k8s.NewAPIClient(tlsConfig, ksm.apiTimelimit)
-	if err != nil {
-		return nil, errors.Wrap(err, "service definition cli")
-	}
-	defer client.CloseIdleConnections()
-
-	reqURL := u.String()
-	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, reqURL)
-	if err != nil {
-		return nil, errors.Wrap(err, "service definition req")
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "kube-state-metrics_service"},
-			cgm.Tag{Category: "target", Value: "api-server"},
-		})
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "kube-state-metrics_service"},
-			cgm.Tag{Category: "target", Value: "api-server"},
-			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
-		})
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			ksm.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
-			return nil, err
-		}
-		ksm.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return nil, errors.New("error response from api server")
-	}
-
-	var s k8s.ServiceList
-	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
-		return nil, err
-	}
-
-	if len(s.Items) == 0 {
-		return nil, errors.New("no 'kube-state-metrics' service found")
-	}
-
-	if len(s.Items) > 1 {
-		return nil, fmt.Errorf("multiple (%d) 'kube-state-metrics' services found", len(s.Items))
-	}
+// responseBody closes the idle connections of its client when closed
+type responseBody struct {
+	io.ReadCloser
+	client *http.Client
+}
 
-	return s.Items[0], nil
+func (b responseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.client.CloseIdleConnections()
+	return err
 }
 
-func (ksm *KSM) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL string) error {
+// requestMetrics requests the metrics of an instance, the caller
+// closes the returned body
+func (ksm *KSM) requestMetrics(tlsConfig *tls.Config, metricURL string) (io.ReadCloser, error) {
 	client, err := k8s.NewAPIClient(tlsConfig, ksm.apiTimelimit)
 	if err != nil {
-		return errors.Wrap(err, "/metrics cli")
+		return nil, errors.Wrap(err, "/metrics cli")
 	}
-	defer client.CloseIdleConnections()
 
 	req, err := k8s.NewAPIRequest(ksm.config.BearerToken, metricURL)
 	if err != nil {
-		return errors.Wrap(err, "/metrics req")
+		client.CloseIdleConnections()
+		return nil, errors.Wrap(err, "/metrics req")
 	}
 
 	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		client.CloseIdleConnections()
 		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
 			cgm.Tag{Category: "source", Value: release.NAME},
 			cgm.Tag{Category: "request", Value: "metrics"},
 			cgm.Tag{Category: "proxy", Value: "api-server"},
 			cgm.Tag{Category: "target", Value: "kube-state-metrics"},
 		})
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	body := responseBody{ReadCloser: resp.Body, client: client}
 	ksm.check.AddHistSample("collect_latency", cgm.Tags{
 		cgm.Tag{Category: "source", Value: release.NAME},
 		cgm.Tag{Category: "request", Value: "metrics"},
@@ -267,6 +272,7 @@ func (ksm *KSM) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL st
 	}, float64(time.Since(start).Milliseconds()))
 
 	if resp.StatusCode != http.StatusOK {
+		defer body.Close()
 		ksm.check.IncrementCounter("collect_api_errors", cgm.Tags{
 			cgm.Tag{Category: "source", Value: release.NAME},
 			cgm.Tag{Category: "request", Value: "metrics"},
@@ -277,25 +283,45 @@ func (ksm *KSM) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL st
 		data, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			ksm.log.Error().Err(err).Str("url", metricURL).Msg("reading response")
-			return err
+			return nil, err
 		}
 		ksm.log.Warn().Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return errors.New("error response from api server")
+		return nil, errors.New("error response from api server")
 	}
 
+	return body, nil
+}
+
+// metrics parses and queues the metrics of an instance
+func (ksm *KSM) metrics(ctx context.Context, body io.Reader, shard string, shardTags []string, dedup *seriesOwners) error {
 	streamTags := []string{
 		"source:kube-state-metrics",
 		"source_type:metrics",
 		"__rollup:false", // prevent high cardinality metrics from rolling up
 	}
+	streamTags = append(streamTags, shardTags...)
 	measurementTags := []string{}
+	opts := &promtext.Options{
+		Filter: ksm.filter.Series(ksm.pods.Lookup),
+		Tags:   ksm.tags,
+		Rates:  ksm.rates,
+	}
+	if dedup != nil {
+		series := opts.Filter
+		opts.Filter = func(name string, labels map[string]string) bool {
+			if series != nil && !series(name, labels) {
+				return false
+			}
+			return dedup.claim(shard, name, labels)
+		}
+	}
 
 	// if ksm.check.StreamMetrics() {
-	// 	if err := promtext.StreamMetrics(ctx, ksm.check, ksm.log, resp.Body, streamTags, measurementTags, ksm.ts); err != nil {
+	// 	if err := promtext.StreamMetrics(ctx, ksm.check, ksm.log, body, streamTags, measurementTags, ksm.ts); err != nil {
 	// 		return err
 	// 	}
 	// } else {
-	if err := promtext.QueueMetrics(ctx, ksm.check, ksm.log, resp.Body, streamTags, measurementTags, ksm.ts); err != nil {
+	if err := promtext.QueueMetrics(ctx, ksm.check, ksm.log, body, streamTags, measurementTags, ksm.ts, opts); err != nil {
 		return err
 	}
 	// }
@@ -303,7 +329,7 @@ func (ksm *KSM) metrics(ctx context.Context, tlsConfig *tls.Config, metricURL st
 	return nil
 }
 
-func (ksm *KSM) telemetry(ctx context.Context, tlsConfig *tls.Config, telemetryURL string) error {
+func (ksm *KSM) telemetry(ctx context.Context, tlsConfig *tls.Config, telemetryURL string, shardTags []string) error {
 	client, err := k8s.NewAPIClient(tlsConfig, ksm.apiTimelimit)
 	if err != nil {
 		return errors.Wrap(err, "/telemetry cli")
@@ -357,6 +383,7 @@ func (ksm *KSM) telemetry(ctx context.Context, tlsConfig *tls.Config, telemetryU
 		"source_type:telemetry",
 		"__rollup:false", // prevent high cardinality metrics from rolling up
 	}
+	streamTags = append(streamTags, shardTags...)
 	measurementTags := []string{}
 
 	// if ksm.check.StreamMetrics() {
@@ -364,7 +391,7 @@ func (ksm *KSM) telemetry(ctx context.Context, tlsConfig *tls.Config, telemetryU
 	// 		return err
 	// 	}
 	// } else {
-	if err := promtext.QueueMetrics(ctx, ksm.check, ksm.log, resp.Body, streamTags, measurementTags, ksm.ts); err != nil {
+	if err := promtext.QueueMetrics(ctx, ksm.check, ksm.log, resp.Body, streamTags, measurementTags, ksm.ts, &promtext.Options{Tags: ksm.tags, Rates: ksm.rates}); err != nil {
 		return err
 	}
 	// }
@@ -3,12 +3,15 @@
 // license that can be found in the LICENSE file.
 //
 
-// Package ms is the metrics-server collector
+// Package ms is the metrics-server collector, it collects node, pod, and
+// container cpu and memory usage from the resource metrics api
+// (metrics.k8s.io) served by metrics-server
 package ms
 
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -19,27 +22,49 @@ import (
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
-	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+const (
+	// metricsAPI is the resource metrics api path
+	metricsAPI = "/apis/metrics.k8s.io/v1beta1"
+	// apiServicePath is the registration of the resource metrics api, the
+	// service it references is the source of the metrics
+	apiServicePath = "/apis/apiregistration.k8s.io/v1/apiservices/v1beta1.metrics.k8s.io"
+)
+
+// usageResources are the resources the metrics api reports usage for
+var usageResources = []string{"cpu", "memory"}
+
 type MS struct {
 	config       *config.Cluster
 	check        *circonus.Check
 	log          zerolog.Logger
 	running      bool
 	apiTimelimit time.Duration
+	filter       *filter.Filter  // nil=all namespaces and pods
+	pods         *podcache.Cache // nil=filter pods by namespace only
 	sync.Mutex
 }
 
-// NOTES:
-// determine if pod is even running in cluster:
-// curl localhost:8080/api/v1/pods?labelSelector=k8s-app%3Dmetrics-server
-// will return 200 but the PodList returned will have 0 items
-// of course, requires it's still labeled "k8s-app:metrics-server"
+func init() {
+	registry.Register("metrics-server", func(env registry.Env) (registry.Collector, error) {
+		ms, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		ms.filter = env.Filter
+		ms.pods = env.ClusterPods
+		return ms, nil
+	})
+}
 
 func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*MS, error) {
 	if cfg == nil {
@@ -79,6 +104,7 @@ func (ms *MS) ID() string {
 	return "metrics-server"
 }
 
+// Collect node, pod, and container resource usage from the metrics api
 func (ms *MS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
 	ms.Lock()
 	if ms.running {
@@ -92,33 +118,161 @@ func (ms *MS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time)
 	defer func() {
 		if r := recover(); r != nil {
 			ms.log.Error().Interface("panic", r).Msg("recover")
-			ms.Lock()
-			ms.running = false
-			ms.Unlock()
 		}
+		ms.Lock()
+		ms.running = false
+		ms.Unlock()
 	}()
 
 	collectStart := time.Now()
 
-	metricsURL := ms.config.URL + "/metrics"
+	baseStreamTags := []string{
+		"source:metrics-server",
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	if svc := ms.metricsService(tlsConfig); svc != "" {
+		baseStreamTags = append(baseStreamTags, "metrics_service:"+svc)
+	}
 
-	client, err := k8s.NewAPIClient(tlsConfig, ms.apiTimelimit)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var nodes k8s.NodeMetricsList
+		if err := ms.apiGet(tlsConfig, "metrics_nodes", ms.config.URL+metricsAPI+"/nodes", &nodes); err != nil {
+			ms.log.Error().Err(err).Msg("node metrics")
+			return
+		}
+		ms.queueNodes(ctx, nodes.Items, baseStreamTags, ts)
+	}()
+	go func() {
+		defer wg.Done()
+		var pods k8s.PodMetricsList
+		if err := ms.apiGet(tlsConfig, "metrics_pods", ms.config.URL+metricsAPI+"/pods", &pods); err != nil {
+			ms.log.Error().Err(err).Msg("pod metrics")
+			return
+		}
+		ms.queuePods(ctx, pods.Items, baseStreamTags, ts)
+	}()
+	wg.Wait()
+
+	ms.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_metrics-server"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	ms.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("metrics-server collect end")
+}
+
+// queueNodes emits the usage of each node
+func (ms *MS) queueNodes(ctx context.Context, nodes []k8s.NodeMetrics, baseStreamTags []string, ts *time.Time) {
+	metrics := make(map[string]circonus.MetricSample)
+	for _, node := range nodes {
+		var streamTags []string
+		streamTags = append(streamTags, baseStreamTags...)
+		streamTags = append(streamTags, "node:"+node.Metadata.Name)
+		sampleTS := sampleTime(node.Timestamp, ts)
+		ms.queueUsage(metrics, node.Usage, streamTags, sampleTS)
+		ms.queueWindow(metrics, node.Window, streamTags, sampleTS)
+	}
+	ms.submit(ctx, metrics)
+}
+
+// queuePods emits the usage of each pod (sum of its containers) and container
+func (ms *MS) queuePods(ctx context.Context, pods []k8s.PodMetrics, baseStreamTags []string, ts *time.Time) {
+	include := ms.filter.Series(ms.pods.Lookup)
+	metrics := make(map[string]circonus.MetricSample)
+	for _, pod := range pods {
+		if include != nil && !include("", map[string]string{"namespace": pod.Metadata.Namespace, "pod": pod.Metadata.Name}) {
+			continue
+		}
+		var streamTags []string
+		streamTags = append(streamTags, baseStreamTags...)
+		streamTags = append(streamTags, "pod:"+pod.Metadata.Name, "namespace:"+pod.Metadata.Namespace)
+		sampleTS := sampleTime(pod.Timestamp, ts)
+
+		usage := make(map[string]resource.Quantity)
+		for _, container := range pod.Containers {
+			var containerTags []string
+			containerTags = append(containerTags, streamTags...)
+			containerTags = append(containerTags, "container_name:"+container.Name)
+			ms.queueUsage(metrics, container.Usage, containerTags, sampleTS)
+			for name, qty := range container.Usage {
+				sum := usage[name]
+				sum.Add(qty)
+				usage[name] = sum
+			}
+		}
+		ms.queueUsage(metrics, usage, streamTags, sampleTS)
+		ms.queueWindow(metrics, pod.Window, streamTags, sampleTS)
+	}
+	ms.submit(ctx, metrics)
+}
+
+// queueUsage emits usage by resource, cpu in cores and memory (working set) in bytes
+func (ms *MS) queueUsage(metrics map[string]circonus.MetricSample, usage map[string]resource.Quantity, parentStreamTags []string, ts *time.Time) {
+	for _, name := range usageResources {
+		qty, ok := usage[name]
+		if !ok {
+			continue
+		}
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		streamTags = append(streamTags, "resource:"+name, "units:"+usageUnits(name))
+		_ = ms.check.QueueMetricSample(metrics, "usage", circonus.MetricTypeFloat64, streamTags, nil, usageValue(name, qty), ts)
+	}
+}
+
+// queueWindow emits the window the usage was measured over
+func (ms *MS) queueWindow(metrics map[string]circonus.MetricSample, window string, parentStreamTags []string, ts *time.Time) {
+	if window == "" {
+		return
+	}
+	d, err := time.ParseDuration(window)
 	if err != nil {
-		ms.log.Error().Err(err).Str("url", metricsURL).Msg("metrics cli")
-		ms.Lock()
-		ms.running = false
-		ms.Unlock()
+		ms.log.Debug().Err(err).Str("window", window).Msg("parsing usage window")
 		return
 	}
+	var streamTags []string
+	streamTags = append(streamTags, parentStreamTags...)
+	streamTags = append(streamTags, "units:seconds")
+	_ = ms.check.QueueMetricSample(metrics, "usage_window", circonus.MetricTypeFloat64, streamTags, nil, d.Seconds(), ts)
+}
+
+func (ms *MS) submit(ctx context.Context, metrics map[string]circonus.MetricSample) {
+	if len(metrics) == 0 {
+		return
+	}
+	if err := ms.check.SubmitQueue(ctx, metrics, ms.log); err != nil {
+		ms.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// metricsService returns the service (namespace/name) serving the metrics
+// api, blank if the api is served locally or the registration cannot be read
+func (ms *MS) metricsService(tlsConfig *tls.Config) string {
+	var svc k8s.APIService
+	if err := ms.apiGet(tlsConfig, "metrics_apiservice", ms.config.URL+apiServicePath, &svc); err != nil {
+		ms.log.Debug().Err(err).Msg("metrics api service")
+		return ""
+	}
+	if svc.Spec.Service == nil {
+		return ""
+	}
+	return svc.Spec.Service.Namespace + "/" + svc.Spec.Service.Name
+}
+
+// apiGet decodes the json response of an api server request
+func (ms *MS) apiGet(tlsConfig *tls.Config, request, reqURL string, v interface{}) error {
+	client, err := k8s.NewAPIClient(tlsConfig, ms.apiTimelimit)
+	if err != nil {
+		return errors.Wrap(err, request+" cli")
+	}
 	defer client.CloseIdleConnections()
 
-	req, err := k8s.NewAPIRequest(ms.config.BearerToken, metricsURL)
+	req, err := k8s.NewAPIRequest(ms.config.BearerToken, reqURL)
 	if err != nil {
-		ms.log.Error().Err(err).Str("url", metricsURL).Msg("metrics req")
-		ms.Lock()
-		ms.running = false
-		ms.Unlock()
-		return
+		return errors.Wrap(err, request+" req")
 	}
 
 	start := time.Now()
@@ -126,65 +280,60 @@ func (ms *MS) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time)
 	if err != nil {
 		ms.check.IncrementCounter("collect_api_errors", cgm.Tags{
 			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "request", Value: request},
 			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "metric-server"},
+			cgm.Tag{Category: "target", Value: "metrics-server"},
 		})
-		ms.log.Error().Err(err).Str("url", metricsURL).Msg("metrics")
-		ms.Lock()
-		ms.running = false
-		ms.Unlock()
-		return
+		return err
 	}
 	defer resp.Body.Close()
 	ms.check.AddHistSample("collect_latency", cgm.Tags{
 		cgm.Tag{Category: "source", Value: release.NAME},
-		cgm.Tag{Category: "request", Value: "metrics"},
+		cgm.Tag{Category: "request", Value: request},
 		cgm.Tag{Category: "proxy", Value: "api-server"},
-		cgm.Tag{Category: "target", Value: "metric-server"},
+		cgm.Tag{Category: "target", Value: "metrics-server"},
 		cgm.Tag{Category: "units", Value: "milliseconds"},
 	}, float64(time.Since(start).Milliseconds()))
 
 	if resp.StatusCode != http.StatusOK {
 		ms.check.IncrementCounter("collect_api_errors", cgm.Tags{
 			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "request", Value: request},
 			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "metric-server"},
+			cgm.Tag{Category: "target", Value: "metrics-server"},
 			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
 		})
 		data, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			ms.log.Error().Err(err).Str("url", metricsURL).Msg("reading response")
-			return
+			ms.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
+			return err
 		}
-		ms.log.Warn().Str("url", metricsURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return
+		ms.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		return errors.New("error response from api server")
 	}
 
-	streamTags := []string{
-		"source:metrics-server",
-		"__rollup:false", // prevent high cardinality metrics from rolling up
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// sampleTime returns the time usage was sampled, ts when not reported
+func sampleTime(sampled time.Time, ts *time.Time) *time.Time {
+	if sampled.IsZero() {
+		return ts
 	}
-	measurementTags := []string{}
+	return &sampled
+}
 
-	// if ms.check.StreamMetrics() {
-	// 	if err := promtext.StreamMetrics(ctx, ms.check, ms.log, resp.Body, streamTags, measurementTags, ts); err != nil {
-	// 		ms.log.Error().Err(err).Msg("formatting metrics")
-	// 	}
-	// } else {
-	if err := promtext.QueueMetrics(ctx, ms.check, ms.log, resp.Body, streamTags, measurementTags, ts); err != nil {
-		ms.log.Error().Err(err).Msg("formatting metrics")
+// usageValue returns usage in cores for cpu and bytes otherwise
+func usageValue(name string, qty resource.Quantity) float64 {
+	if name == "cpu" {
+		return float64(qty.ScaledValue(resource.Nano)) / 1e9 // metrics api reports nanocores
 	}
-	// }
+	return float64(qty.Value())
+}
 
-	ms.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "source", Value: release.NAME},
-		cgm.Tag{Category: "opt", Value: "collect_metrics-server"},
-		cgm.Tag{Category: "units", Value: "milliseconds"},
-	}, float64(time.Since(collectStart).Milliseconds()))
-	ms.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("metric-server collect end")
-	ms.Lock()
-	ms.running = false
-	ms.Unlock()
+func usageUnits(name string) string {
+	if name == "cpu" {
+		return "cores"
+	}
+	return "bytes"
 }
@@ -5,8 +5,29 @@
 
 package ms
 
-import "testing"
+import (
+	"testing"
 
-func Test(t *testing.T) {
-	t.Log("Placeholder...nothing to test currently")
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestUsageValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		qty    string
+		expect float64
+	}{
+		{"cpu", "250m", 0.25},
+		{"cpu", "123456n", 0.000123456},
+		{"cpu", "2", 2},
+		{"memory", "1Ki", 1024},
+		{"memory", "52428800", 52428800},
+	}
+
+	for _, test := range tests {
+		v := usageValue(test.name, resource.MustParse(test.qty))
+		if v != test.expect {
+			t.Errorf("%s %s expected %v, got %v", test.name, test.qty, test.expect, v)
+		}
+	}
 }
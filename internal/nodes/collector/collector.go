@@ -13,20 +13,25 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	cgm "github.com/circonus-labs/circonus-gometrics/v3"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workload"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
-
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Collector struct {
@@ -34,14 +39,27 @@ type Collector struct {
 	tlsConfig    *tls.Config
 	ctx          context.Context
 	check        *circonus.Check
+	pods         *podcache.Cache
+	filter       *filter.Filter
+	tags         *tagrules.Rules
+	workloads    *workload.Resolver
+	rates        *rates.Store
+	kubeletTLS   *tls.Config
 	node         *k8s.Node
 	baseLogger   zerolog.Logger
 	log          zerolog.Logger
 	ts           *time.Time
 	apiTimelimit time.Duration
+	podMeta      map[string]podMetaResult // pod metadata looked up during this collection
+	podMetaMu    sync.Mutex
+}
+
+type podMetaResult struct {
+	meta *podMeta
+	err  error
 }
 
-func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circonus.Check, apiTimeout time.Duration) (*Collector, error) {
+func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circonus.Check, pods *podcache.Cache, f *filter.Filter, tags *tagrules.Rules, workloads *workload.Resolver, counters *rates.Store, kubeletTLS *tls.Config, apiTimeout time.Duration) (*Collector, error) {
 	if cfg == nil {
 		return nil, errors.New("invalid cluster config (nil)")
 	}
@@ -55,8 +73,15 @@ func New(cfg *config.Cluster, node *k8s.Node, logger zerolog.Logger, check *circ
 	return &Collector{
 		cfg:          cfg,
 		check:        check,
+		pods:         pods,
+		filter:       f,
+		tags:         tags,
+		workloads:    workloads,
+		rates:        counters,
+		kubeletTLS:   kubeletTLS,
 		node:         node,
 		apiTimelimit: apiTimeout,
+		podMeta:      make(map[string]podMetaResult),
 		baseLogger:   logger.With().Str("node", node.Metadata.Name).Logger(),
 	}, nil
 }
@@ -100,6 +125,20 @@ func (nc *Collector) Collect(ctx context.Context, workerID int, tlsConfig *tls.C
 			wg.Done()
 		}()
 	}
+	if nc.cfg.EnableResourceMetrics {
+		wg.Add(1)
+		go func() {
+			nc.resource(baseStreamTags, baseMeasurementTags) // from /metrics/resource
+			wg.Done()
+		}()
+	}
+	if nc.cfg.EnableProbeMetrics {
+		wg.Add(1)
+		go func() {
+			nc.probes(baseStreamTags, baseMeasurementTags) // from /metrics/probes
+			wg.Done()
+		}()
+	}
 
 	wg.Wait()
 
@@ -114,6 +153,17 @@ func (nc *Collector) Collect(ctx context.Context, workerID int, tlsConfig *tls.C
 		Msg("node collect end")
 }
 
+// CollectMeta emits only the node meta stats, conditions, and resources
+// (from the node list) for a node which is not ready, the kubelet is not queried
+func (nc *Collector) CollectMeta(ctx context.Context, workerID int, tlsConfig *tls.Config, ts *time.Time) {
+	nc.ctx = ctx
+	nc.tlsConfig = tlsConfig
+	nc.ts = ts
+	nc.log = nc.baseLogger.With().Int("worker_id", workerID).Logger()
+
+	nc.meta([]string{"source:kubelet", "node:" + nc.node.Metadata.Name}, []string{})
+}
+
 // meta emits node meta stats
 func (nc *Collector) meta(parentStreamTags []string, parentMeasurementTags []string) {
 	if nc.done() {
@@ -236,9 +286,7 @@ func (nc *Collector) meta(parentStreamTags []string, parentMeasurementTags []str
 			"os_image:" + nc.node.Status.NodeInfo.OSImage,
 			"kublet_version:" + nc.node.Status.NodeInfo.KubeletVersion,
 		}...)
-		for k, v := range nc.node.Metadata.Labels {
-			streamTags = append(streamTags, k+":"+v)
-		}
+		streamTags = append(streamTags, nc.tags.Tags(nc.node.Metadata.Labels)...)
 		_ = nc.check.QueueMetricSample(
 			metrics,
 			"node",
@@ -266,60 +314,11 @@ func (nc *Collector) meta(parentStreamTags []string, parentMeasurementTags []str
 		}
 	}
 
-	{ // capacity and allocatable
-		var streamTags []string
-		streamTags = append(streamTags, parentStreamTags...)
-		if v, err := strconv.Atoi(nc.node.Status.Capacity.CPU); err == nil {
-			_ = nc.check.QueueMetricSample(
-				metrics,
-				"capacity_cpu",
-				circonus.MetricTypeUint64,
-				streamTags, parentMeasurementTags,
-				uint64(v),
-				nc.ts)
-		} else {
-			nc.log.Warn().Err(err).Str("cpu", nc.node.Status.Capacity.CPU).Msg("converting capacity.cpu")
-		}
-		if v, err := strconv.Atoi(nc.node.Status.Capacity.Pods); err == nil {
-			_ = nc.check.QueueMetricSample(
-				metrics,
-				"capacity_pods",
-				circonus.MetricTypeUint64,
-				streamTags, parentMeasurementTags,
-				uint64(v),
-				nc.ts)
-		} else {
-			nc.log.Warn().Err(err).Str("pods", nc.node.Status.Capacity.Pods).Msg("converting capacity.pods")
-		}
-		if qty, err := resource.ParseQuantity(nc.node.Status.Capacity.Memory); err == nil {
-			if mem, ok := qty.AsInt64(); ok {
-				streamTags = append(streamTags, "units:bytes")
-				_ = nc.check.QueueMetricSample(
-					metrics,
-					"capacity_memory",
-					circonus.MetricTypeUint64,
-					streamTags, parentMeasurementTags,
-					uint64(mem),
-					nc.ts)
-			}
-		} else {
-			nc.log.Warn().Err(err).Str("memory", nc.node.Status.Capacity.Memory).Msg("parsing quantity capacity.memory")
-		}
-		if qty, err := resource.ParseQuantity(nc.node.Status.Capacity.EphemeralStorage); err == nil {
-			if storage, ok := qty.AsInt64(); ok {
-				streamTags = append(streamTags, "units:bytes")
-				_ = nc.check.QueueMetricSample(
-					metrics,
-					"capacity_ephemeral_storage",
-					circonus.MetricTypeUint64,
-					streamTags, parentMeasurementTags,
-					uint64(storage),
-					nc.ts)
-			}
-		} else {
-			nc.log.Warn().Err(err).Str("ephemeral_storage", nc.node.Status.Capacity.EphemeralStorage).Msg("parsing quantity capacity.ephemeral-storage")
-		}
-	}
+	// numeric conditions, transition age, cordon and taints
+	nc.queueConditions(metrics, parentStreamTags, parentMeasurementTags)
+
+	// capacity, allocatable, and reserved
+	nc.queueResources(metrics, parentStreamTags, parentMeasurementTags)
 
 	if len(metrics) == 0 {
 		nc.log.Warn().Msg("no telemetry to submit")
@@ -348,18 +347,21 @@ type statsSummaryNode struct {
 }
 
 type cpu struct {
-	UsageNanoCores       uint64 `json:"usageNanoCores"`
-	UsageCoreNanoSeconds uint64 `json:"usageCoreNanoSeconds"`
+	Time                 time.Time `json:"time"`
+	UsageNanoCores       uint64    `json:"usageNanoCores"`
+	UsageCoreNanoSeconds uint64    `json:"usageCoreNanoSeconds"`
 }
 type memory struct {
-	AvailableBytes  uint64 `json:"availableBytes"`
-	UsageBytes      uint64 `json:"usageBytes"`
-	WorkingSetBytes uint64 `json:"workingSetBytes"`
-	RSSBytes        uint64 `json:"rssBytes"`
-	PageFaults      uint64 `json:"pageFaults"`
-	MajorPageFaults uint64 `json:"majorPageFaults"`
+	Time            time.Time `json:"time"`
+	AvailableBytes  uint64    `json:"availableBytes"`
+	UsageBytes      uint64    `json:"usageBytes"`
+	WorkingSetBytes uint64    `json:"workingSetBytes"`
+	RSSBytes        uint64    `json:"rssBytes"`
+	PageFaults      uint64    `json:"pageFaults"`
+	MajorPageFaults uint64    `json:"majorPageFaults"`
 }
 type network struct {
+	Time time.Time `json:"time"`
 	networkInterface
 	Interfaces []networkInterface `json:"interfaces"`
 }
@@ -416,62 +418,16 @@ func (nc *Collector) summary(parentStreamTags []string, parentMeasurementTags []
 		return
 	}
 
-	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
-	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning collection")
-		return
-	}
-	defer client.CloseIdleConnections()
-
-	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/stats/summary"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
-	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning collection")
-		return
-	}
-
-	start := time.Now()
-	resp, err := client.Do(req)
+	resp, err := nc.kubeletGet("stats/summary", "/stats/summary")
 	if err != nil {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "stats/summary"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-		})
-		nc.log.Error().Err(err).Str("req_url", reqURL).Msg("fetching summary stats")
+		nc.log.Error().Err(err).Msg("fetching summary stats")
 		return
 	}
-	nc.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "source", Value: release.NAME},
-		cgm.Tag{Category: "request", Value: "stats/summary"},
-		cgm.Tag{Category: "proxy", Value: "api-server"},
-		cgm.Tag{Category: "target", Value: "kubelet"},
-		cgm.Tag{Category: "units", Value: "milliseconds"},
-	}, float64(time.Since(start).Milliseconds()))
-
 	defer resp.Body.Close()
 	if nc.done() {
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "stats/summary"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
-		})
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			nc.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
-			return
-		}
-		nc.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return
-	}
-
 	var stats statsSummary
 	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
 		nc.log.Error().Err(err).Msg("parsing summary stats")
@@ -703,6 +659,15 @@ func (nc *Collector) summaryPods(stats *statsSummary, parentStreamTags []string,
 			nc.queueVolume(metrics, &volume, podStreamTags, parentMeasurementTags)
 		}
 
+		var specs []containerResources
+		if meta, err := nc.podMetadata(pod.PodRef.Namespace, pod.PodRef.Name); err == nil {
+			specs = meta.Containers
+		}
+		if len(specs) > 0 {
+			requests, limits := podResources(specs)
+			nc.queueRequests(metrics, podUsage(&pod), requests, limits, podStreamTags, parentMeasurementTags)
+		}
+
 		if nc.cfg.IncludeContainers {
 			for _, container := range pod.Containers {
 				if nc.done() {
@@ -712,6 +677,13 @@ func (nc *Collector) summaryPods(stats *statsSummary, parentStreamTags []string,
 				streamTagList = append(streamTagList, podStreamTags...)
 				streamTagList = append(streamTagList, "container_name:"+container.Name)
 
+				for _, spec := range specs {
+					if spec.Name == container.Name {
+						nc.queueRequests(metrics, containerUsage(&container), spec.Requests, spec.Limits, streamTagList, parentMeasurementTags)
+						break
+					}
+				}
+
 				nc.queueCPU(metrics, &container.CPU, streamTagList, parentMeasurementTags)
 				nc.queueMemory(metrics, &container.Memory, streamTagList, parentMeasurementTags, false)
 				if container.RootFS.CapacityBytes > 0 { // rootfs
@@ -740,67 +712,22 @@ func (nc *Collector) nmetrics(parentStreamTags []string, parentMeasurementTags [
 		return
 	}
 
-	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
-	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning /metrics collection")
-		return
-	}
-	defer client.CloseIdleConnections()
-
-	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/metrics"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
-	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning /metrics collection")
-		return
-	}
-
-	start := time.Now()
-	resp, err := client.Do(req)
+	resp, err := nc.kubeletGet("metrics", "/metrics")
 	if err != nil {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-		})
-		nc.log.Error().Err(err).Str("url", reqURL).Msg("node metrics")
+		nc.log.Error().Err(err).Msg("node metrics")
 		return
 	}
-	nc.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "request", Value: "metrics"},
-		cgm.Tag{Category: "proxy", Value: "api-server"},
-		cgm.Tag{Category: "target", Value: "kubelet"},
-		cgm.Tag{Category: "units", Value: "milliseconds"},
-	}, float64(time.Since(start).Milliseconds()))
-
 	defer resp.Body.Close()
 	if nc.done() {
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
-		})
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			nc.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
-			return
-		}
-		nc.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return
-	}
-
 	// if nc.check.StreamMetrics() {
 	// 	if err := promtext.StreamMetrics(nc.ctx, nc.check, nc.log, resp.Body, parentStreamTags, parentMeasurementTags, nc.ts); err != nil {
 	// 		nc.log.Error().Err(err).Msg("parsing node metrics")
 	// 	}
 	// } else {
-	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, parentStreamTags, parentMeasurementTags, nil); err != nil {
+	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, parentStreamTags, parentMeasurementTags, nil, &promtext.Options{Tags: nc.tags, Rates: nc.rates}); err != nil {
 		nc.log.Error().Err(err).Msg("parsing node metrics")
 	}
 	// }
@@ -812,90 +739,242 @@ func (nc *Collector) cadvisor(parentStreamTags []string, parentMeasurementTags [
 		return
 	}
 
-	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
+	resp, err := nc.kubeletGet("metrics/cadvisor", "/metrics/cadvisor")
 	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning /metrics/cadvisor collection")
+		nc.log.Error().Err(err).Msg("node metrics/cadvisor")
+		return
+	}
+	defer resp.Body.Close()
+	if nc.done() {
 		return
 	}
-	defer client.CloseIdleConnections()
 
-	reqURL := nc.cfg.URL + nc.node.Metadata.SelfLink + "/proxy/metrics/cadvisor"
-	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
-	if err != nil {
-		nc.log.Error().Err(err).Msg("abandoning /metrics/cadvisor collection")
+	streamTags := []string{"__rollup:false"} // prevent high cardinality metrics from rolling up
+	streamTags = append(streamTags, parentStreamTags...)
+
+	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, streamTags, parentMeasurementTags, nil, nc.podSeriesOptions()); err != nil {
+		nc.log.Error().Err(err).Msg("parsing node metrics/cadvisor")
+	}
+}
+
+// resource emits metrics from the node /metrics/resource endpoint, cpu and
+// memory usage of the node, pods, and containers (kubelet 1.18+)
+func (nc *Collector) resource(parentStreamTags []string, parentMeasurementTags []string) {
+	if nc.done() {
 		return
 	}
 
-	start := time.Now()
-	resp, err := client.Do(req)
+	resp, err := nc.kubeletGet("metrics/resource", "/metrics/resource")
 	if err != nil {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-		})
-		nc.log.Error().Err(err).Str("url", reqURL).Msg("node metrics/cadvisor")
+		nc.log.Error().Err(err).Msg("node metrics/resource")
 		return
 	}
-	nc.check.AddHistSample("collect_latency", cgm.Tags{
-		cgm.Tag{Category: "request", Value: "metrics/cadvsior"},
-		cgm.Tag{Category: "proxy", Value: "api-server"},
-		cgm.Tag{Category: "target", Value: "kubelet"},
-		cgm.Tag{Category: "units", Value: "milliseconds"},
-	}, float64(time.Since(start).Milliseconds()))
-
 	defer resp.Body.Close()
 	if nc.done() {
 		return
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
-			cgm.Tag{Category: "source", Value: release.NAME},
-			cgm.Tag{Category: "request", Value: "metrics/cadvisor"},
-			cgm.Tag{Category: "proxy", Value: "api-server"},
-			cgm.Tag{Category: "target", Value: "kubelet"},
-			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
-		})
-		data, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			nc.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
-			return
+	streamTags := []string{"__rollup:false", "source_type:resource"} // prevent high cardinality metrics from rolling up
+	streamTags = append(streamTags, parentStreamTags...)
+
+	opts := nc.podSeriesOptions()
+	series := opts.Filter
+	opts.Filter = func(name string, labels map[string]string) bool {
+		if !nc.includeResourceSeries(name) {
+			return false
 		}
-		nc.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
+		if series != nil {
+			return series(name, labels)
+		}
+		return true
+	}
+
+	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, streamTags, parentMeasurementTags, nil, opts); err != nil {
+		nc.log.Error().Err(err).Msg("parsing node metrics/resource")
+	}
+}
+
+// includeResourceSeries returns whether to emit a /metrics/resource
+// series, pod and container series follow the include pods and
+// include containers settings
+func (nc *Collector) includeResourceSeries(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "container_"):
+		return nc.cfg.IncludePods && nc.cfg.IncludeContainers
+	case strings.HasPrefix(name, "pod_"):
+		return nc.cfg.IncludePods
+	default:
+		return true
+	}
+}
+
+// probes emits metrics from the node /metrics/probes endpoint, liveness,
+// readiness, and startup probe results by container (kubelet 1.18+)
+func (nc *Collector) probes(parentStreamTags []string, parentMeasurementTags []string) {
+	if nc.done() {
 		return
 	}
 
-	streamTags := []string{"__rollup:false"} // prevent high cardinality metrics from rolling up
+	resp, err := nc.kubeletGet("metrics/probes", "/metrics/probes")
+	if err != nil {
+		nc.log.Error().Err(err).Msg("node metrics/probes")
+		return
+	}
+	defer resp.Body.Close()
+	if nc.done() {
+		return
+	}
+
+	streamTags := []string{"__rollup:false", "source_type:probes"} // prevent high cardinality metrics from rolling up
 	streamTags = append(streamTags, parentStreamTags...)
 
-	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, streamTags, parentMeasurementTags, nil); err != nil {
-		nc.log.Error().Err(err).Msg("parsing node metrics/cadvisor")
+	if err := promtext.QueueMetrics(nc.ctx, nc.check, nc.log, resp.Body, streamTags, parentMeasurementTags, nil, nc.podSeriesOptions()); err != nil {
+		nc.log.Error().Err(err).Msg("parsing node metrics/probes")
+	}
+}
+
+// podSeriesOptions returns the options for kubelet prometheus endpoints
+// with pod series, series are filtered by namespace and pod and tagged
+// with the pod's workload
+func (nc *Collector) podSeriesOptions() *promtext.Options {
+	return &promtext.Options{
+		Filter: nc.filter.Series(func(ns, name string) (map[string]string, map[string]string, bool) {
+			if !nc.cfg.IncludePods {
+				return nil, nil, false // pod metadata is only retrieved when collecting pods
+			}
+			meta, err := nc.podMetadata(ns, name)
+			if err != nil {
+				return nil, nil, false
+			}
+			return meta.Labels, meta.Annotations, true
+		}),
+		Tags:  nc.tags,
+		Rates: nc.rates,
+		ExtraTags: func(_ string, labels map[string]string) []string {
+			ns, name := seriesPod(labels)
+			if ns == "" || name == "" || !nc.cfg.IncludePods {
+				return nil
+			}
+			meta, err := nc.podMetadata(ns, name)
+			if err != nil {
+				return nil
+			}
+			return nc.workloadTags(ns, meta.OwnerReferences)
+		},
 	}
 }
 
 type podSpec struct {
 	Metadata podMeta `json:"metadata"`
+	Spec     struct {
+		Containers []struct {
+			Name      string `json:"name"`
+			Resources struct {
+				Requests map[string]resource.Quantity `json:"requests"`
+				Limits   map[string]resource.Quantity `json:"limits"`
+			} `json:"resources"`
+		} `json:"containers"`
+	} `json:"spec"`
 }
 type podMeta struct {
-	Labels map[string]string `json:"labels"`
+	Labels          map[string]string       `json:"labels"`
+	Annotations     map[string]string       `json:"annotations"`
+	OwnerReferences []metav1.OwnerReference `json:"ownerReferences"`
+	Containers      []containerResources    `json:"-"`
 }
 
+// getPodLabels returns whether to collect metrics for a pod (see filter)
+// and its labels and workload as tags
 func (nc *Collector) getPodLabels(ns string, name string) (bool, []string, error) {
-	collect := false
 	tags := []string{}
 
+	meta, err := nc.podMetadata(ns, name)
+	if err != nil {
+		return false, tags, err
+	}
+
+	if !nc.filter.Pod(ns, meta.Labels, meta.Annotations) {
+		return false, tags, nil
+	}
+
+	tags = append(tags, nc.tags.Tags(meta.Labels)...)
+	tags = append(tags, nc.workloadTags(ns, meta.OwnerReferences)...)
+
+	return true, tags, nil
+}
+
+// workloadTags returns the workload_kind and workload_name tags for a pod
+func (nc *Collector) workloadTags(ns string, owners []metav1.OwnerReference) []string {
+	kind, name := nc.workloads.Resolve(ns, owners)
+	if kind == "" {
+		return nil
+	}
+	var tags []string
+	if tag, ok := nc.tags.Tag("workload_kind", kind); ok {
+		tags = append(tags, tag)
+	}
+	if tag, ok := nc.tags.Tag("workload_name", name); ok {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// podMetadata returns the metadata of a pod, using the pod cache when
+// available and the api server otherwise, results are kept for the
+// duration of the node collection
+func (nc *Collector) podMetadata(ns string, name string) (*podMeta, error) {
+	key := ns + "/" + name
+
+	nc.podMetaMu.Lock()
+	defer nc.podMetaMu.Unlock()
+
+	if r, ok := nc.podMeta[key]; ok {
+		return r.meta, r.err
+	}
+
+	var r podMetaResult
+	if pod, ok := nc.pods.Get(ns, name); ok {
+		r.meta = &podMeta{
+			Labels:          pod.Labels,
+			Annotations:     pod.Annotations,
+			OwnerReferences: pod.OwnerReferences,
+		}
+		for _, c := range pod.Containers {
+			r.meta.Containers = append(r.meta.Containers, containerResources{
+				Name:     c.Name,
+				Requests: resourceList(c.Requests),
+				Limits:   resourceList(c.Limits),
+			})
+		}
+	} else {
+		r.meta, r.err = nc.fetchPodMeta(ns, name)
+	}
+	nc.podMeta[key] = r
+
+	return r.meta, r.err
+}
+
+// seriesPod returns the namespace and pod of a prometheus series, blank if not a pod series
+func seriesPod(labels map[string]string) (string, string) {
+	pod := labels["pod"]
+	if pod == "" {
+		pod = labels["pod_name"]
+	}
+	return labels["namespace"], pod
+}
+
+// fetchPodMeta retrieves the metadata for a pod from the api server
+func (nc *Collector) fetchPodMeta(ns string, name string) (*podMeta, error) {
 	client, err := k8s.NewAPIClient(nc.tlsConfig, nc.apiTimelimit)
 	if err != nil {
-		return collect, tags, err
+		return nil, err
 	}
 	defer client.CloseIdleConnections()
 
 	reqURL := nc.cfg.URL + "/api/v1/namespaces/" + ns + "/pods/" + name
 	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
 	if err != nil {
-		return collect, tags, err
+		return nil, err
 	}
 
 	start := time.Now()
@@ -906,7 +985,7 @@ func (nc *Collector) getPodLabels(ns string, name string) (bool, []string, error
 			cgm.Tag{Category: "request", Value: "pod-labels"},
 			cgm.Tag{Category: "target", Value: "api-server"},
 		})
-		return collect, tags, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	nc.check.AddHistSample("collect_latency", cgm.Tags{
@@ -926,34 +1005,26 @@ func (nc *Collector) getPodLabels(ns string, name string) (bool, []string, error
 		data, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
 			nc.log.Error().Err(err).Str("url", reqURL).Msg("reading response")
-			return collect, nil, err
+			return nil, err
 		}
 		nc.log.Warn().Str("url", reqURL).Str("status", resp.Status).RawJSON("response", data).Msg("error from API server")
-		return collect, nil, errors.Errorf("error from api %s (%s)", resp.Status, string(data))
+		return nil, errors.Errorf("error from api %s (%s)", resp.Status, string(data))
 	}
 
 	var ps podSpec
 	if err := json.NewDecoder(resp.Body).Decode(&ps); err != nil {
-		return collect, tags, err
-	}
-
-	collect = true
-	if nc.cfg.PodLabelKey != "" {
-		collect = false
-		if v, ok := ps.Metadata.Labels[nc.cfg.PodLabelKey]; ok {
-			if nc.cfg.PodLabelVal == "" {
-				collect = true
-			} else if v == nc.cfg.PodLabelVal {
-				collect = true
-			}
-		}
+		return nil, err
 	}
 
-	for k, v := range ps.Metadata.Labels {
-		tags = append(tags, k+":"+v)
+	for _, c := range ps.Spec.Containers {
+		ps.Metadata.Containers = append(ps.Metadata.Containers, containerResources{
+			Name:     c.Name,
+			Requests: c.Resources.Requests,
+			Limits:   c.Resources.Limits,
+		})
 	}
 
-	return collect, tags, nil
+	return &ps.Metadata, nil
 }
 
 func (nc *Collector) done() bool {
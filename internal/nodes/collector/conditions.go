@@ -0,0 +1,92 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+// taintEffects are always emitted so a removed taint goes to zero
+var taintEffects = []string{"NoSchedule", "PreferNoSchedule", "NoExecute"}
+
+// queueConditions emits numeric node conditions (1=True, 0=False,
+// -1=Unknown), seconds since each condition last transitioned, and
+// the cordon (unschedulable) and taint state of the node
+func (nc *Collector) queueConditions(metrics map[string]circonus.MetricSample, parentStreamTags []string, parentMeasurementTags []string) {
+	now := time.Now()
+	if nc.ts != nil {
+		now = *nc.ts
+	}
+
+	for _, cond := range nc.node.Status.Conditions {
+		if nc.done() {
+			break
+		}
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		streamTags = append(streamTags, "condition:"+cond.Type)
+		_ = nc.check.QueueMetricSample(metrics, "condition", circonus.MetricTypeInt32, streamTags, parentMeasurementTags, conditionValue(cond.Status), nc.ts)
+
+		if cond.LastTransitionTime.IsZero() {
+			continue
+		}
+		streamTags = append(streamTags, "units:seconds")
+		_ = nc.check.QueueMetricSample(metrics, "condition_transition_seconds", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, transitionSeconds(cond.LastTransitionTime, now), nc.ts)
+	}
+
+	unschedulable := uint64(0)
+	if nc.node.Spec.Unschedulable {
+		unschedulable = 1
+	}
+	_ = nc.check.QueueMetricSample(metrics, "unschedulable", circonus.MetricTypeUint64, parentStreamTags, parentMeasurementTags, unschedulable, nc.ts)
+
+	for effect, count := range taintCounts(nc.node.Spec.Taints) {
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		streamTags = append(streamTags, "effect:"+effect)
+		_ = nc.check.QueueMetricSample(metrics, "taints", circonus.MetricTypeUint64, streamTags, parentMeasurementTags, count, nc.ts)
+	}
+}
+
+// conditionValue returns the numeric value of a condition status
+func conditionValue(status string) int32 {
+	switch status {
+	case "True":
+		return 1
+	case "False":
+		return 0
+	default:
+		return -1
+	}
+}
+
+// transitionSeconds returns the whole seconds since a condition
+// transitioned, zero if the transition is in the future (clock skew)
+func transitionSeconds(transition, now time.Time) uint64 {
+	d := now.Sub(transition)
+	if d < 0 {
+		return 0
+	}
+	return uint64(d / time.Second)
+}
+
+// taintCounts returns the number of taints by effect
+func taintCounts(taints []k8s.NodeTaint) map[string]uint64 {
+	counts := make(map[string]uint64, len(taintEffects))
+	for _, effect := range taintEffects {
+		counts[effect] = 0
+	}
+	for _, taint := range taints {
+		if taint.Effect == "" {
+			continue
+		}
+		counts[taint.Effect]++
+	}
+	return counts
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+)
+
+func TestConditionValue(t *testing.T) {
+	tests := map[string]int32{
+		"True":    1,
+		"False":   0,
+		"Unknown": -1,
+		"":        -1,
+	}
+
+	for status, want := range tests {
+		if got := conditionValue(status); got != want {
+			t.Fatalf("%q: expected %d, got %d", status, want, got)
+		}
+	}
+}
+
+func TestTransitionSeconds(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	if got := transitionSeconds(now.Add(-90*time.Second), now); got != 90 {
+		t.Fatalf("expected 90, got %d", got)
+	}
+	if got := transitionSeconds(now.Add(time.Minute), now); got != 0 {
+		t.Fatalf("expected 0 (future), got %d", got)
+	}
+}
+
+func TestTaintCounts(t *testing.T) {
+	counts := taintCounts([]k8s.NodeTaint{
+		{Key: "node.kubernetes.io/unschedulable", Effect: "NoSchedule"},
+		{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"},
+		{Key: "node.kubernetes.io/unreachable", Effect: "NoExecute"},
+	})
+
+	expected := map[string]uint64{"NoSchedule": 2, "PreferNoSchedule": 0, "NoExecute": 1}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, counts)
+	}
+	for effect, want := range expected {
+		if counts[effect] != want {
+			t.Fatalf("%s: expected %d, got %d", effect, want, counts[effect])
+		}
+	}
+}
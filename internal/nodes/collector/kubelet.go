@@ -0,0 +1,129 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KubeletProxy reaches kubelets via the api server node proxy
+	KubeletProxy = "proxy"
+	// KubeletDirect reaches kubelets directly on the node InternalIP
+	KubeletDirect = "direct"
+)
+
+// kubeletGet requests path (e.g. /stats/summary) from the kubelet of the
+// node, directly when configured or collecting the local node (falling
+// back to the api server proxy if the direct request fails) otherwise via
+// the api server proxy. The caller must close the response body.
+func (nc *Collector) kubeletGet(request, path string) (*http.Response, error) {
+	if nc.cfg.KubeletMode == KubeletDirect || nc.cfg.LocalNode {
+		baseURL, err := nc.kubeletURL()
+		if err == nil {
+			var resp *http.Response
+			resp, err = nc.kubeletRequest(request, "none", baseURL+path, nc.kubeletTLS)
+			if err == nil {
+				return resp, nil
+			}
+		}
+		nc.check.IncrementCounter("collect_kubelet_fallbacks", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+		})
+		nc.log.Warn().Err(err).Str("request", request).Msg("direct kubelet request failed, using api-server proxy")
+	}
+
+	return nc.kubeletRequest(request, "api-server", nc.cfg.URL+"/api/v1/nodes/"+nc.node.Metadata.Name+"/proxy"+path, nc.tlsConfig)
+}
+
+// kubeletURL returns the base url to reach the kubelet of the node directly
+func (nc *Collector) kubeletURL() (string, error) {
+	ip := ""
+	for _, addr := range nc.node.Status.Addresses {
+		if addr.Type == "InternalIP" {
+			ip = addr.Address
+			break
+		}
+	}
+	if ip == "" {
+		return "", errors.New("node has no InternalIP address")
+	}
+
+	port := int(nc.cfg.KubeletPort)
+	if port == 0 {
+		port = nc.node.Status.DaemonEndpoints.KubeletEndpoint.Port
+	}
+	if port == 0 {
+		port = defaults.K8SKubeletDefaultPort
+	}
+
+	return "https://" + net.JoinHostPort(ip, strconv.Itoa(port)), nil
+}
+
+// kubeletRequest makes a request, a non-200 response is returned as an error
+func (nc *Collector) kubeletRequest(request, proxy, reqURL string, tlsConfig *tls.Config) (*http.Response, error) {
+	client, err := k8s.NewAPIClient(tlsConfig, nc.apiTimelimit)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubelet client")
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := k8s.NewAPIRequest(nc.cfg.BearerToken, reqURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "kubelet request")
+	}
+	req = req.WithContext(nc.ctx)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "proxy", Value: proxy},
+			cgm.Tag{Category: "target", Value: "kubelet"},
+		})
+		return nil, errors.Wrapf(err, "fetching %s", reqURL)
+	}
+	nc.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "request", Value: request},
+		cgm.Tag{Category: "proxy", Value: proxy},
+		cgm.Tag{Category: "target", Value: "kubelet"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(start).Milliseconds()))
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		nc.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: request},
+			cgm.Tag{Category: "proxy", Value: proxy},
+			cgm.Tag{Category: "target", Value: "kubelet"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading response %s (%s)", reqURL, resp.Status)
+		}
+		return nil, errors.Errorf("error from %s %s (%s)", reqURL, resp.Status, string(data))
+	}
+
+	return resp, nil
+}
@@ -19,6 +19,7 @@ func (nc *Collector) queueCPU(dest map[string]circonus.MetricSample, stats *cpu,
 	_ = nc.check.QueueMetricSample(dest, cores, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.UsageNanoCores, nc.ts)
 	streamTags = append(streamTags, "units:seconds")
 	_ = nc.check.QueueMetricSample(dest, seconds, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.UsageCoreNanoSeconds, nc.ts)
+	nc.rates.Queue(dest, seconds, streamTags, parentMeasurementTags, float64(stats.UsageCoreNanoSeconds), &stats.Time, nc.ts)
 }
 
 // func (nc *Collector) streamCPU(dest io.Writer, stats *cpu, parentStreamTags []string, parentMeasurementTags []string) {
@@ -59,6 +60,8 @@ func (nc *Collector) queueMemory(dest map[string]circonus.MetricSample, stats *m
 		streamTags = append(streamTags, []string{"resource:memory", "units:faults"}...)
 		_ = nc.check.QueueMetricSample(dest, pageFaults, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.PageFaults, nc.ts)
 		_ = nc.check.QueueMetricSample(dest, majorPageFaults, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.MajorPageFaults, nc.ts)
+		nc.rates.Queue(dest, pageFaults, streamTags, parentMeasurementTags, float64(stats.PageFaults), &stats.Time, nc.ts)
+		nc.rates.Queue(dest, majorPageFaults, streamTags, parentMeasurementTags, float64(stats.MajorPageFaults), &stats.Time, nc.ts)
 	}
 }
 
@@ -99,14 +102,18 @@ func (nc *Collector) queueNetwork(dest map[string]circonus.MetricSample, stats *
 		streamTags = append(streamTags, parentStreamTags...)
 		streamTags = append(streamTags, []string{"resource:network", "units:bytes"}...)
 		_ = nc.check.QueueMetricSample(dest, receive, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.RxBytes, nc.ts)
+		nc.rates.Queue(dest, receive, streamTags, parentMeasurementTags, float64(stats.RxBytes), &stats.Time, nc.ts)
 		_ = nc.check.QueueMetricSample(dest, transmit, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.TxBytes, nc.ts)
+		nc.rates.Queue(dest, transmit, streamTags, parentMeasurementTags, float64(stats.TxBytes), &stats.Time, nc.ts)
 	}
 	{ // units:errors
 		var streamTags []string
 		streamTags = append(streamTags, parentStreamTags...)
 		streamTags = append(streamTags, []string{"resource:network", "units:errors"}...)
 		_ = nc.check.QueueMetricSample(dest, receive, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.RxErrors, nc.ts)
+		nc.rates.Queue(dest, receive, streamTags, parentMeasurementTags, float64(stats.RxErrors), &stats.Time, nc.ts)
 		_ = nc.check.QueueMetricSample(dest, transmit, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, stats.TxErrors, nc.ts)
+		nc.rates.Queue(dest, transmit, streamTags, parentMeasurementTags, float64(stats.TxErrors), &stats.Time, nc.ts)
 	}
 
 	for _, iface := range stats.Interfaces {
@@ -115,14 +122,18 @@ func (nc *Collector) queueNetwork(dest map[string]circonus.MetricSample, stats *
 			streamTags = append(streamTags, parentStreamTags...)
 			streamTags = append(streamTags, []string{"resource:network", "units:bytes", "interface:" + iface.Name}...)
 			_ = nc.check.QueueMetricSample(dest, receive, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.RxBytes, nc.ts)
+			nc.rates.Queue(dest, receive, streamTags, parentMeasurementTags, float64(iface.RxBytes), &stats.Time, nc.ts)
 			_ = nc.check.QueueMetricSample(dest, transmit, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.TxBytes, nc.ts)
+			nc.rates.Queue(dest, transmit, streamTags, parentMeasurementTags, float64(iface.TxBytes), &stats.Time, nc.ts)
 		}
 		{ // units:errors
 			var streamTags []string
 			streamTags = append(streamTags, parentStreamTags...)
 			streamTags = append(streamTags, []string{"resource:network", "units:errors", "interface:" + iface.Name}...)
 			_ = nc.check.QueueMetricSample(dest, receive, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.RxErrors, nc.ts)
+			nc.rates.Queue(dest, receive, streamTags, parentMeasurementTags, float64(iface.RxErrors), &stats.Time, nc.ts)
 			_ = nc.check.QueueMetricSample(dest, transmit, circonus.MetricTypeUint64, streamTags, parentMeasurementTags, iface.TxErrors, nc.ts)
+			nc.rates.Queue(dest, transmit, streamTags, parentMeasurementTags, float64(iface.TxErrors), &stats.Time, nc.ts)
 		}
 	}
 }
@@ -0,0 +1,134 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// requestResources are the resources requests, limits, and usage
+// ratios are emitted for
+var requestResources = []string{"cpu", "memory", "ephemeral-storage"}
+
+// containerResources are the requests and limits from a container spec
+type containerResources struct {
+	Name     string
+	Requests map[string]resource.Quantity
+	Limits   map[string]resource.Quantity
+}
+
+// resourceList converts a pod cache resource list
+func resourceList(rl corev1.ResourceList) map[string]resource.Quantity {
+	if len(rl) == 0 {
+		return nil
+	}
+	qtys := make(map[string]resource.Quantity, len(rl))
+	for name, qty := range rl {
+		qtys[string(name)] = qty
+	}
+	return qtys
+}
+
+// containerUsage returns the usage of a container by resource name,
+// cpu in cores, memory (working set) and ephemeral-storage (rootfs
+// and logs) in bytes
+func containerUsage(c *container) map[string]float64 {
+	return map[string]float64{
+		"cpu":               float64(c.CPU.UsageNanoCores) / 1e9,
+		"memory":            float64(c.Memory.WorkingSetBytes),
+		"ephemeral-storage": float64(c.RootFS.UsedBytes + c.Logs.UsedBytes),
+	}
+}
+
+// podUsage returns the usage of a pod by resource name
+func podUsage(p *pod) map[string]float64 {
+	return map[string]float64{
+		"cpu":               float64(p.CPU.UsageNanoCores) / 1e9,
+		"memory":            float64(p.Memory.WorkingSetBytes),
+		"ephemeral-storage": float64(p.EphemeralStorage.UsedBytes),
+	}
+}
+
+// podResources returns the pod requests (sum of the container requests)
+// and limits (sum of the container limits, only when every container
+// has a limit for the resource, otherwise the pod is unbounded)
+func podResources(containers []containerResources) (map[string]resource.Quantity, map[string]resource.Quantity) {
+	requests := make(map[string]resource.Quantity)
+	limits := make(map[string]resource.Quantity)
+
+	for _, name := range requestResources {
+		for _, c := range containers {
+			if qty, ok := c.Requests[name]; ok {
+				sum := requests[name]
+				sum.Add(qty)
+				requests[name] = sum
+			}
+		}
+
+		bounded := len(containers) > 0
+		var sum resource.Quantity
+		for _, c := range containers {
+			qty, ok := c.Limits[name]
+			if !ok {
+				bounded = false
+				break
+			}
+			sum.Add(qty)
+		}
+		if bounded {
+			limits[name] = sum
+		}
+	}
+
+	return requests, limits
+}
+
+// queueRequests emits the requests, limits, and usage/request and
+// usage/limit ratios of a pod or container
+func (nc *Collector) queueRequests(dest map[string]circonus.MetricSample, usage map[string]float64, requests, limits map[string]resource.Quantity, parentStreamTags []string, parentMeasurementTags []string) {
+	for _, name := range requestResources {
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		streamTags = append(streamTags, "resource:"+name)
+
+		var valueTags []string
+		valueTags = append(valueTags, streamTags...)
+		if name == "cpu" {
+			valueTags = append(valueTags, "units:cores")
+		} else {
+			valueTags = append(valueTags, "units:bytes")
+		}
+
+		for _, rl := range []struct {
+			metric string
+			qtys   map[string]resource.Quantity
+		}{
+			{"request", requests},
+			{"limit", limits},
+		} {
+			qty, ok := rl.qtys[name]
+			if !ok {
+				continue
+			}
+			mtype, value, _ := quantityValue(name, qty)
+			_ = nc.check.QueueMetricSample(dest, rl.metric, mtype, valueTags, parentMeasurementTags, value, nc.ts)
+			v := quantityFloat(name, qty)
+			if u, ok := usage[name]; ok && v > 0 {
+				_ = nc.check.QueueMetricSample(dest, "usage_"+rl.metric+"_ratio", circonus.MetricTypeFloat64, streamTags, parentMeasurementTags, u/v, nc.ts)
+			}
+		}
+	}
+}
+
+// quantityFloat returns a quantity in the units used for usage, cores for cpu and bytes otherwise
+func quantityFloat(name string, qty resource.Quantity) float64 {
+	if name == "cpu" {
+		return float64(qty.MilliValue()) / 1000
+	}
+	return float64(qty.Value())
+}
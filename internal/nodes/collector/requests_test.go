@@ -0,0 +1,53 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func qtys(kv ...string) map[string]resource.Quantity {
+	m := make(map[string]resource.Quantity)
+	for i := 0; i+1 < len(kv); i += 2 {
+		m[kv[i]] = resource.MustParse(kv[i+1])
+	}
+	return m
+}
+
+func TestPodResources(t *testing.T) {
+	containers := []containerResources{
+		{Name: "app", Requests: qtys("cpu", "250m", "memory", "128Mi"), Limits: qtys("cpu", "1", "memory", "256Mi")},
+		{Name: "sidecar", Requests: qtys("cpu", "50m"), Limits: qtys("memory", "64Mi")},
+	}
+
+	requests, limits := podResources(containers)
+
+	if got := quantityFloat("cpu", requests["cpu"]); got != 0.3 {
+		t.Fatalf("expected cpu request 0.3, got %v", got)
+	}
+	if got := quantityFloat("memory", requests["memory"]); got != 134217728 {
+		t.Fatalf("expected memory request 134217728, got %v", got)
+	}
+	if _, ok := limits["cpu"]; ok {
+		t.Fatal("expected no cpu limit, sidecar is unbounded")
+	}
+	if got := quantityFloat("memory", limits["memory"]); got != 335544320 {
+		t.Fatalf("expected memory limit 335544320, got %v", got)
+	}
+	if _, ok := requests["ephemeral-storage"]; ok {
+		t.Fatal("expected no ephemeral-storage request")
+	}
+
+	t.Log("no containers")
+	{
+		requests, limits := podResources(nil)
+		if len(requests) != 0 || len(limits) != 0 {
+			t.Fatalf("expected no requests or limits, got %v %v", requests, limits)
+		}
+	}
+}
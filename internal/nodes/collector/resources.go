@@ -0,0 +1,117 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"strings"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// queueResources emits the node capacity, allocatable, and reserved
+// (capacity minus allocatable, e.g. system and kube reserved) resources
+func (nc *Collector) queueResources(metrics map[string]circonus.MetricSample, parentStreamTags []string, parentMeasurementTags []string) {
+	capacity := nc.parseSizes("capacity", nc.node.Status.Capacity)
+	allocatable := nc.parseSizes("allocatable", nc.node.Status.Allocatable)
+
+	reserved := make(map[string]resource.Quantity)
+	for name, c := range capacity {
+		a, ok := allocatable[name]
+		if !ok {
+			continue
+		}
+		r := c.DeepCopy()
+		r.Sub(a)
+		if r.Sign() < 0 {
+			continue
+		}
+		reserved[name] = r
+	}
+
+	nc.queueSizes(metrics, "capacity", capacity, parentStreamTags, parentMeasurementTags)
+	nc.queueSizes(metrics, "allocatable", allocatable, parentStreamTags, parentMeasurementTags)
+	nc.queueSizes(metrics, "reserved", reserved, parentStreamTags, parentMeasurementTags)
+}
+
+// parseSizes parses the resource quantities of a node, invalid quantities are skipped
+func (nc *Collector) parseSizes(kind string, sizes k8s.NodeSizes) map[string]resource.Quantity {
+	qtys := make(map[string]resource.Quantity, len(sizes))
+	for name, v := range sizes {
+		qty, err := resource.ParseQuantity(v)
+		if err != nil {
+			nc.log.Warn().Err(err).Str("resource", name).Str("quantity", v).Msg("parsing quantity " + kind)
+			continue
+		}
+		qtys[name] = qty
+	}
+	return qtys
+}
+
+func (nc *Collector) queueSizes(metrics map[string]circonus.MetricSample, kind string, qtys map[string]resource.Quantity, parentStreamTags []string, parentMeasurementTags []string) {
+	for name, qty := range qtys {
+		mtype, value, units := quantityValue(name, qty)
+		var streamTags []string
+		streamTags = append(streamTags, parentStreamTags...)
+		if units != "" {
+			streamTags = append(streamTags, "units:"+units)
+		}
+		_ = nc.check.QueueMetricSample(
+			metrics,
+			resourceMetricName(kind, name),
+			mtype,
+			streamTags, parentMeasurementTags,
+			value,
+			nc.ts)
+		if name == "cpu" {
+			// *_cpu stays whole cores (uint64) as it always was,
+			// the fractional value is *_cpu_cores
+			_ = nc.check.QueueMetricSample(
+				metrics,
+				resourceMetricName(kind, name)+"_cores",
+				circonus.MetricTypeFloat64,
+				streamTags, parentMeasurementTags,
+				cpuCores(qty),
+				nc.ts)
+		}
+	}
+}
+
+// quantityValue returns the metric type, value, and units for a resource
+// quantity. cpu is in whole cores (truncated, see cpuCores for the fractional
+// value), memory, storage, and hugepages in bytes, pods a count, extended
+// resources (e.g. nvidia.com/gpu) as a decimal value.
+func quantityValue(name string, qty resource.Quantity) (string, interface{}, string) {
+	switch {
+	case name == "cpu":
+		return circonus.MetricTypeUint64, uint64(qty.MilliValue() / 1000), ""
+	case name == "memory", name == "ephemeral-storage", name == "storage", strings.HasPrefix(name, "hugepages-"):
+		return circonus.MetricTypeUint64, uint64(qty.Value()), "bytes"
+	case name == "pods":
+		return circonus.MetricTypeUint64, uint64(qty.Value()), ""
+	default:
+		return circonus.MetricTypeFloat64, float64(qty.MilliValue()) / 1000, ""
+	}
+}
+
+// cpuCores returns a cpu quantity as a decimal number of cores
+func cpuCores(qty resource.Quantity) float64 {
+	return float64(qty.MilliValue()) / 1000
+}
+
+// resourceMetricName returns the metric name for a resource, e.g.
+// capacity_ephemeral_storage or allocatable_nvidia_com_gpu
+func resourceMetricName(kind, name string) string {
+	return kind + "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
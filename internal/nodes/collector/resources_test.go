@@ -0,0 +1,72 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestQuantityValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		qty   string
+		mtype string
+		value interface{}
+		units string
+	}{
+		{"cpu", "3500m", circonus.MetricTypeUint64, uint64(3), ""},
+		{"cpu", "4", circonus.MetricTypeUint64, uint64(4), ""},
+		{"cpu", "100m", circonus.MetricTypeUint64, uint64(0), ""},
+		{"memory", "16Gi", circonus.MetricTypeUint64, uint64(17179869184), "bytes"},
+		{"ephemeral-storage", "100M", circonus.MetricTypeUint64, uint64(100000000), "bytes"},
+		{"hugepages-2Mi", "0", circonus.MetricTypeUint64, uint64(0), "bytes"},
+		{"pods", "110", circonus.MetricTypeUint64, uint64(110), ""},
+		{"nvidia.com/gpu", "2", circonus.MetricTypeFloat64, float64(2), ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name+"="+tt.qty, func(t *testing.T) {
+			mtype, value, units := quantityValue(tt.name, resource.MustParse(tt.qty))
+			if mtype != tt.mtype || value != tt.value || units != tt.units {
+				t.Fatalf("expected %s %v %q, got %s %v %q", tt.mtype, tt.value, tt.units, mtype, value, units)
+			}
+		})
+	}
+}
+
+func TestCPUCores(t *testing.T) {
+	tests := map[string]float64{
+		"3500m": 3.5,
+		"4":     4,
+		"100m":  0.1,
+		"250u":  0.001, // below millicore precision rounds up
+	}
+
+	for qty, want := range tests {
+		if got := cpuCores(resource.MustParse(qty)); got != want {
+			t.Fatalf("%s: expected %v, got %v", qty, want, got)
+		}
+	}
+}
+
+func TestResourceMetricName(t *testing.T) {
+	tests := map[string]string{
+		"cpu":               "capacity_cpu",
+		"ephemeral-storage": "capacity_ephemeral_storage",
+		"hugepages-2Mi":     "capacity_hugepages_2Mi",
+		"nvidia.com/gpu":    "capacity_nvidia_com_gpu",
+	}
+
+	for name, want := range tests {
+		if got := resourceMetricName("capacity", name); got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
@@ -9,6 +9,7 @@ package nodes
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -21,9 +22,16 @@ import (
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/nodes/collector"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/shard"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workload"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
@@ -34,9 +42,32 @@ type Nodes struct {
 	log          zerolog.Logger
 	running      bool
 	apiTimelimit time.Duration
+	shard        *shard.Shard       // nil=collect from all nodes
+	pods         *podcache.Cache    // nil=fetch pod metadata from api server
+	filter       *filter.Filter     // nil=all namespaces and pods
+	tags         *tagrules.Rules    // nil=all labels become tags
+	workloads    *workload.Resolver // nil=pod controller is the workload
+	rates        *rates.Store       // nil=no counter rates or deltas
+	kubeletTLS   *tls.Config        // nil=use api server tls config
 	sync.Mutex
 }
 
+func init() {
+	registry.Register("nodes", func(env registry.Env) (registry.Collector, error) {
+		n, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		n.shard = env.Shard
+		n.pods = env.Pods
+		n.filter = env.Filter
+		n.tags = env.Tags
+		n.workloads = env.Workloads
+		n.rates = env.Rates
+		return n, nil
+	})
+}
+
 func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Nodes, error) {
 	if cfg == nil {
 		return nil, errors.New("invalid cluster config (nil)")
@@ -68,6 +99,30 @@ func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (
 		nodes.apiTimelimit = v
 	}
 
+	switch cfg.KubeletMode {
+	case "", collector.KubeletProxy, collector.KubeletDirect:
+	default:
+		return nil, errors.Errorf("invalid kubelet mode (%s)", cfg.KubeletMode)
+	}
+
+	// the local kubelet is always scraped directly when collecting the local node
+	if cfg.KubeletMode == collector.KubeletDirect || cfg.LocalNode {
+		switch {
+		case cfg.KubeletInsecure:
+			nodes.kubeletTLS = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
+		case cfg.KubeletCAFile != "":
+			cert, err := ioutil.ReadFile(cfg.KubeletCAFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "configuring kubelet tls")
+			}
+			cp := x509.NewCertPool()
+			if !cp.AppendCertsFromPEM(cert) {
+				return nil, errors.New("unable to add kubelet CA Certificate to x509 cert pool")
+			}
+			nodes.kubeletTLS = &tls.Config{RootCAs: cp}
+		}
+	}
+
 	return nodes, nil
 }
 
@@ -75,6 +130,11 @@ func (n *Nodes) ID() string {
 	return "nodes"
 }
 
+// Scope is node, when sharding each replica collects from the nodes it owns
+func (n *Nodes) Scope() registry.Scope {
+	return registry.ScopeNode
+}
+
 func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Time) {
 	n.Lock()
 	if n.running {
@@ -105,8 +165,13 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 		return
 	}
 
+	kubeletTLS := n.kubeletTLS
+	if kubeletTLS == nil {
+		kubeletTLS = tlsConfig
+	}
+
 	maxCollectors := int(n.config.NodePoolSize)
-	nodeQueue := make(chan *collector.Collector)
+	nodeQueue := make(chan nodeJob)
 	var wg sync.WaitGroup
 	n.log.Debug().
 		Int("num_workers", maxCollectors).
@@ -115,14 +180,18 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 	for i := 0; i < maxCollectors; i++ {
 		wg.Add(1)
 		id := i
-		go func(nodeQueue chan *collector.Collector, id int) {
+		go func(nodeQueue chan nodeJob, id int) {
 			defer wg.Done()
 			workStart := time.Now()
 			n.log.Debug().
 				Int("worker_id", id).
 				Msg("worker started")
-			for node := range nodeQueue {
-				node.Collect(ctx, id, tlsConfig, ts)
+			for job := range nodeQueue {
+				if job.metaOnly {
+					job.nc.CollectMeta(ctx, id, tlsConfig, ts)
+					continue
+				}
+				job.nc.Collect(ctx, id, tlsConfig, ts)
 			}
 			n.log.Debug().
 				Str("duration", time.Since(workStart).String()).
@@ -131,39 +200,62 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 		}(nodeQueue, id)
 	}
 
+	var counts nodeCounts
 	nodesQueued := 0
+	nodesOwned := 0
 	for _, node := range nodes.Items {
 		node := node
-		for _, cond := range node.Status.Conditions {
-			if cond.Type != "Ready" {
-				continue
-			}
-			if cond.Status == "True" {
-				nc, err := collector.New(n.config, &node, n.log, n.check, n.apiTimelimit)
-				if err != nil {
-					n.log.Error().Err(err).Str("node", node.Metadata.Name).Msg("skipping...")
-					break
-				}
-				nodeQueue <- nc
-				nodesQueued++
-			} else {
-				n.log.Warn().Str(cond.Type, cond.Status).Str("node", node.Metadata.Name).Msg("skipping...")
+		if n.shard != nil && !n.shard.Owns(node.Metadata.Name) {
+			continue
+		}
+		nodesOwned++
+		if node.Spec.Unschedulable {
+			counts.unschedulable++
+		}
+		ready := node.Ready()
+		if ready == "True" {
+			counts.ready++
+		} else {
+			counts.notReady++
+			counts.skipped++
+			n.log.Warn().Str("Ready", ready).Str("node", node.Metadata.Name).Msg("skipping kubelet collection, node not ready")
+		}
+		nc, err := collector.New(n.config, &node, n.log, n.check, n.pods, n.filter, n.tags, n.workloads, n.rates, kubeletTLS, n.apiTimelimit)
+		if err != nil {
+			if ready == "True" {
+				counts.skipped++
 			}
-			break
+			n.log.Error().Err(err).Str("node", node.Metadata.Name).Msg("skipping...")
+			continue
+		}
+		// not ready nodes still emit conditions and resources from the node list
+		nodeQueue <- nodeJob{nc: nc, metaOnly: ready != "True"}
+		if ready == "True" {
+			nodesQueued++
 		}
 	}
 	close(nodeQueue)
 	wg.Wait() // wait for last one to finish
 
+	n.addNodeCounts(counts)
+
 	n.check.AddHistSample("collect_latency", cgm.Tags{
 		cgm.Tag{Category: "type", Value: "collect_nodes"},
 		cgm.Tag{Category: "source", Value: "agent"},
 		cgm.Tag{Category: "units", Value: "milliseconds"},
 	}, float64(time.Since(collectStart).Milliseconds()))
 
+	if n.shard != nil {
+		n.check.AddGauge("collect_shard_nodes", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "shard", Value: n.shard.Identity()},
+		}, uint64(nodesOwned))
+	}
+
 	n.log.Debug().
 		Str("duration", time.Since(collectStart).String()).
 		Int("nodes_queued", nodesQueued).
+		Int("nodes_owned", nodesOwned).
 		Int("nodes_total", len(nodes.Items)).
 		Int("node_workers", maxCollectors).
 		Msg("node collect end")
@@ -172,17 +264,48 @@ func (n *Nodes) Collect(ctx context.Context, tlsConfig *tls.Config, ts *time.Tim
 	n.Unlock()
 }
 
+// nodeJob is a node queued for collection
+type nodeJob struct {
+	nc       *collector.Collector
+	metaOnly bool // node is not ready, only emit metrics from the node list
+}
+
+// nodeCounts are the node states seen in a collection
+type nodeCounts struct {
+	ready         uint64
+	notReady      uint64 // Ready condition False, Unknown, or missing
+	unschedulable uint64 // cordoned
+	skipped       uint64 // kubelet not collected (not ready or error)
+}
+
+// addNodeCounts emits the number of ready, not ready, unschedulable,
+// and skipped nodes (of the nodes owned by this shard when sharding)
+func (n *Nodes) addNodeCounts(counts nodeCounts) {
+	tags := cgm.Tags{cgm.Tag{Category: "source", Value: release.NAME}}
+	if n.shard != nil {
+		tags = append(tags, cgm.Tag{Category: "shard", Value: n.shard.Identity()})
+	}
+	n.check.AddGauge("nodes_ready", tags, counts.ready)
+	n.check.AddGauge("nodes_not_ready", tags, counts.notReady)
+	n.check.AddGauge("nodes_unschedulable", tags, counts.unschedulable)
+	n.check.AddGauge("nodes_skipped", tags, counts.skipped)
+}
+
 func (n *Nodes) nodeList(tlsConfig *tls.Config) (*k8s.NodeList, error) {
 	u, err := url.Parse(n.config.URL + "/api/v1/nodes")
 	if err != nil {
 		return nil, err
 	}
 
+	q := u.Query()
 	if labelSelector := n.config.NodeSelector; labelSelector != "" {
-		q := u.Query()
 		q.Set("labelSelector", labelSelector)
-		u.RawQuery = q.Encode()
 	}
+	if n.config.LocalNode {
+		// only the node the agent is running on, not the full node list
+		q.Set("fieldSelector", "metadata.name="+n.config.NodeName)
+	}
+	u.RawQuery = q.Encode()
 
 	client, err := k8s.NewAPIClient(tlsConfig, n.apiTimelimit)
 	if err != nil {
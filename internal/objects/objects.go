@@ -0,0 +1,291 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package objects is the object state collector, it emits the core
+// object state series (deployments, pods, containers, namespaces, and
+// jobs) from a watch based cache, without requiring kube-state-metrics.
+// Metric names and labels match kube-state-metrics where they overlap,
+// so the same metric filters and dashboards apply.
+package objects
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resyncPeriod is how often the informers replay the cached objects
+const resyncPeriod = 10 * time.Minute
+
+// Objects is the object state collector
+type Objects struct {
+	config    *config.Cluster
+	check     *circonus.Check
+	log       zerolog.Logger
+	clientset *kubernetes.Clientset
+	pods      *podcache.Cache // shared pod cache, nil=pods not collected
+	filter    *filter.Filter  // nil=all namespaces and pods
+	tags      *tagrules.Rules // nil=all labels become tags
+	rates     *rates.Store    // nil=no counter rates or deltas
+	listers   *listers        // nil=watch not started or not synced
+	running   bool
+	sync.Mutex
+}
+
+type listers struct {
+	deployments appslisters.DeploymentLister
+	namespaces  corelisters.NamespaceLister
+	jobs        batchlisters.JobLister
+}
+
+func init() {
+	registry.Register("objects", func(env registry.Env) (registry.Collector, error) {
+		o, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		o.pods = env.ClusterPods
+		o.filter = env.Filter
+		o.tags = env.Tags
+		o.rates = env.Rates
+		return o, nil
+	})
+}
+
+// New returns a new object state collector, nothing is collected
+// until the watch is started and the cache has synced
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Objects, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	clientset, err := k8s.NewClientset(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "objects collector")
+	}
+
+	return &Objects{
+		config:    cfg,
+		check:     check,
+		log:       parentLog.With().Str("collector", "objects").Logger(),
+		clientset: clientset,
+	}, nil
+}
+
+func (o *Objects) ID() string {
+	return "objects"
+}
+
+// Watch watches deployments, namespaces, and jobs until ctx is done,
+// pods are taken from the cluster's shared pod cache
+func (o *Objects) Watch(ctx context.Context) {
+	defer runtime.HandleCrash()
+
+	factory := informers.NewSharedInformerFactory(o.clientset, resyncPeriod)
+	deployments := factory.Apps().V1().Deployments()
+	namespaces := factory.Core().V1().Namespaces()
+	jobs := factory.Batch().V1().Jobs()
+	synced := []cache.InformerSynced{
+		deployments.Informer().HasSynced,
+		namespaces.Informer().HasSynced,
+		jobs.Informer().HasSynced,
+	}
+
+	l := &listers{
+		deployments: deployments.Lister(),
+		namespaces:  namespaces.Lister(),
+		jobs:        jobs.Lister(),
+	}
+
+	o.log.Info().Msg("starting object watch")
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		o.log.Warn().Msg("object cache did not sync")
+		return
+	}
+
+	o.Lock()
+	o.listers = l
+	o.Unlock()
+	o.log.Info().Msg("object cache synced")
+
+	<-ctx.Done()
+
+	o.Lock()
+	o.listers = nil
+	o.Unlock()
+	o.log.Debug().Msg("stopped object watch")
+}
+
+// Collect emits the object state series from the cache
+func (o *Objects) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	o.Lock()
+	if o.running {
+		o.log.Warn().Msg("already running")
+		o.Unlock()
+		return
+	}
+	l := o.listers
+	if l == nil {
+		o.log.Warn().Msg("object cache not synced, skipping")
+		o.Unlock()
+		return
+	}
+	o.running = true
+	o.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			o.log.Error().Interface("panic", r).Msg("recover")
+		}
+		o.Lock()
+		o.running = false
+		o.Unlock()
+	}()
+
+	collectStart := time.Now()
+
+	metrics := make(map[string]circonus.MetricSample)
+	baseStreamTags := []string{
+		"source:objects",
+		"source_type:objects",
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	maxMetrics := o.check.MaxMetricBucketSize()
+	queue := func(list []series) {
+		for _, s := range list {
+			if maxMetrics > 0 && len(metrics) >= maxMetrics {
+				if err := o.check.SubmitQueue(ctx, metrics, o.log); err != nil {
+					o.log.Warn().Err(err).Msg("submitting metrics")
+				}
+				metrics = make(map[string]circonus.MetricSample)
+			}
+			streamTags := o.labelTags(s.labels)
+			streamTags = append(streamTags, baseStreamTags...)
+			_ = o.check.QueueMetricSample(metrics, s.name, circonus.MetricTypeFloat64, streamTags, nil, s.value, ts)
+			if s.counter {
+				o.rates.Queue(metrics, s.name, streamTags, nil, s.value, nil, ts)
+			}
+		}
+	}
+	counts := make(map[string]int)
+
+	if list, err := l.namespaces.List(labels.Everything()); err != nil {
+		o.log.Warn().Err(err).Msg("listing namespaces")
+	} else {
+		for _, n := range list {
+			if !o.filter.Namespace(n.Name) {
+				continue
+			}
+			counts["namespace"]++
+			queue(namespaceSeries(n))
+		}
+	}
+
+	if list, err := l.deployments.List(labels.Everything()); err != nil {
+		o.log.Warn().Err(err).Msg("listing deployments")
+	} else {
+		for _, d := range list {
+			if !o.filter.Namespace(d.Namespace) {
+				continue
+			}
+			counts["deployment"]++
+			queue(deploymentSeries(d))
+		}
+	}
+
+	if list, ok := o.pods.Pods(); !ok {
+		o.log.Warn().Msg("pod cache not synced, skipping pods")
+	} else {
+		for _, p := range list {
+			if ctx.Err() != nil {
+				break
+			}
+			if !o.filter.Pod(p.Namespace, p.Labels, p.Annotations) {
+				continue
+			}
+			counts["pod"]++
+			queue(podSeries(p))
+		}
+	}
+
+	if list, err := l.jobs.List(labels.Everything()); err != nil {
+		o.log.Warn().Err(err).Msg("listing jobs")
+	} else {
+		for _, j := range list {
+			if !o.filter.Namespace(j.Namespace) {
+				continue
+			}
+			counts["job"]++
+			queue(jobSeries(j))
+		}
+	}
+
+	if len(metrics) > 0 {
+		if err := o.check.SubmitQueue(ctx, metrics, o.log); err != nil {
+			o.log.Warn().Err(err).Msg("submitting metrics")
+		}
+	}
+
+	for kind, n := range counts {
+		o.check.AddGauge("collect_objects", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "kind", Value: kind},
+		}, uint64(n))
+	}
+	o.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_objects"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	o.log.Debug().Str("duration", time.Since(collectStart).String()).Msg("objects collect end")
+}
+
+// labelTags returns the stream tags for series labels, in label name order
+func (o *Objects) labelTags(seriesLabels map[string]string) []string {
+	keys := make([]string, 0, len(seriesLabels))
+	for k := range seriesLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make([]string, 0, len(keys)+2)
+	for _, k := range keys {
+		if seriesLabels[k] == "" {
+			continue
+		}
+		if tag, ok := o.tags.Tag(k, seriesLabels[k]); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
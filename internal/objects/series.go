@@ -0,0 +1,178 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package objects
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// series is an object state sample, names and labels
+// match kube-state-metrics where they overlap
+type series struct {
+	name    string
+	labels  map[string]string
+	value   float64
+	counter bool // cumulative, eligible for counter rates and deltas
+}
+
+var (
+	podPhases = []corev1.PodPhase{
+		corev1.PodPending,
+		corev1.PodRunning,
+		corev1.PodSucceeded,
+		corev1.PodFailed,
+		corev1.PodUnknown,
+	}
+	namespacePhases = []corev1.NamespacePhase{
+		corev1.NamespaceActive,
+		corev1.NamespaceTerminating,
+	}
+	conditionStatuses = []corev1.ConditionStatus{
+		corev1.ConditionTrue,
+		corev1.ConditionFalse,
+		corev1.ConditionUnknown,
+	}
+)
+
+// deploymentSeries returns the replica series of a deployment
+func deploymentSeries(d *appsv1.Deployment) []series {
+	labels := map[string]string{"namespace": d.Namespace, "deployment": d.Name}
+
+	replicas := int32(1) // api default when not set
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	out := []series{
+		{name: "kube_deployment_spec_replicas", labels: labels, value: float64(replicas)},
+		{name: "kube_deployment_status_replicas", labels: labels, value: float64(d.Status.Replicas)},
+		{name: "kube_deployment_status_replicas_available", labels: labels, value: float64(d.Status.AvailableReplicas)},
+		{name: "kube_deployment_status_replicas_unavailable", labels: labels, value: float64(d.Status.UnavailableReplicas)},
+		{name: "kube_deployment_status_replicas_updated", labels: labels, value: float64(d.Status.UpdatedReplicas)},
+	}
+	if !d.CreationTimestamp.IsZero() {
+		out = append(out, series{name: "kube_deployment_created", labels: labels, value: float64(d.CreationTimestamp.Unix())})
+	}
+
+	return out
+}
+
+// podSeries returns the phase, readiness, and container status series of a pod
+func podSeries(p *corev1.Pod) []series {
+	labels := map[string]string{"namespace": p.Namespace, "pod": p.Name}
+
+	var out []series
+	for _, phase := range podPhases {
+		out = append(out, series{
+			name:   "kube_pod_status_phase",
+			labels: with(labels, "phase", string(phase)),
+			value:  boolValue(p.Status.Phase == phase),
+		})
+	}
+	if p.Status.StartTime != nil {
+		out = append(out, series{name: "kube_pod_start_time", labels: labels, value: float64(p.Status.StartTime.Unix())})
+	}
+	for _, c := range p.Status.Conditions {
+		switch c.Type {
+		case corev1.PodReady:
+			out = append(out, conditionSeries("kube_pod_status_ready", labels, c.Status)...)
+		case corev1.PodScheduled:
+			out = append(out, conditionSeries("kube_pod_status_scheduled", labels, c.Status)...)
+		}
+	}
+
+	for _, cs := range p.Status.ContainerStatuses {
+		clabels := with(labels, "container", cs.Name)
+		out = append(out,
+			series{name: "kube_pod_container_status_restarts_total", labels: clabels, value: float64(cs.RestartCount), counter: true},
+			series{name: "kube_pod_container_status_ready", labels: clabels, value: boolValue(cs.Ready)},
+			series{name: "kube_pod_container_status_running", labels: clabels, value: boolValue(cs.State.Running != nil)},
+			series{name: "kube_pod_container_status_terminated", labels: clabels, value: boolValue(cs.State.Terminated != nil)},
+			series{name: "kube_pod_container_status_waiting", labels: clabels, value: boolValue(cs.State.Waiting != nil)},
+		)
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			out = append(out, series{
+				name:   "kube_pod_container_status_waiting_reason",
+				labels: with(clabels, "reason", cs.State.Waiting.Reason),
+				value:  1,
+			})
+		}
+	}
+
+	return out
+}
+
+// namespaceSeries returns the phase series of a namespace
+func namespaceSeries(n *corev1.Namespace) []series {
+	labels := map[string]string{"namespace": n.Name}
+
+	out := make([]series, 0, len(namespacePhases))
+	for _, phase := range namespacePhases {
+		out = append(out, series{
+			name:   "kube_namespace_status_phase",
+			labels: with(labels, "phase", string(phase)),
+			value:  boolValue(n.Status.Phase == phase),
+		})
+	}
+
+	return out
+}
+
+// jobSeries returns the pod counts and completion series of a job
+func jobSeries(j *batchv1.Job) []series {
+	labels := map[string]string{"namespace": j.Namespace, "job_name": j.Name}
+
+	out := []series{
+		{name: "kube_job_status_active", labels: labels, value: float64(j.Status.Active)},
+		{name: "kube_job_status_succeeded", labels: labels, value: float64(j.Status.Succeeded)},
+		{name: "kube_job_status_failed", labels: labels, value: float64(j.Status.Failed)},
+	}
+	for _, c := range j.Status.Conditions {
+		switch c.Type {
+		case batchv1.JobComplete:
+			out = append(out, conditionSeries("kube_job_complete", labels, c.Status)...)
+		case batchv1.JobFailed:
+			out = append(out, conditionSeries("kube_job_failed", labels, c.Status)...)
+		}
+	}
+
+	return out
+}
+
+// conditionSeries returns a series for each condition status (true,
+// false, unknown), 1 for the current status of the condition
+func conditionSeries(name string, labels map[string]string, status corev1.ConditionStatus) []series {
+	out := make([]series, 0, len(conditionStatuses))
+	for _, cs := range conditionStatuses {
+		out = append(out, series{
+			name:   name,
+			labels: with(labels, "condition", strings.ToLower(string(cs))),
+			value:  boolValue(status == cs),
+		})
+	}
+	return out
+}
+
+// with returns a copy of labels with an additional label
+func with(labels map[string]string, key, value string) map[string]string {
+	l := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		l[k] = v
+	}
+	l[key] = value
+	return l
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package objects
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// find returns the value of the series with name and labels, false if not found
+func find(list []series, name string, labels map[string]string) (float64, bool) {
+	for _, s := range list {
+		if s.name != name || len(s.labels) != len(labels) {
+			continue
+		}
+		match := true
+		for k, v := range labels {
+			if s.labels[k] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			return s.value, true
+		}
+	}
+	return 0, false
+}
+
+func TestDeploymentSeries(t *testing.T) {
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Status: appsv1.DeploymentStatus{
+			Replicas:            3,
+			AvailableReplicas:   2,
+			UnavailableReplicas: 1,
+		},
+	}
+	labels := map[string]string{"namespace": "default", "deployment": "web"}
+
+	list := deploymentSeries(d)
+	tests := []struct {
+		name   string
+		expect float64
+	}{
+		{"kube_deployment_spec_replicas", 1}, // default when not set
+		{"kube_deployment_status_replicas", 3},
+		{"kube_deployment_status_replicas_available", 2},
+		{"kube_deployment_status_replicas_unavailable", 1},
+	}
+	for _, test := range tests {
+		v, ok := find(list, test.name, labels)
+		if !ok {
+			t.Fatalf("%s not found", test.name)
+		}
+		if v != test.expect {
+			t.Fatalf("%s expected %v, got %v", test.name, test.expect, v)
+		}
+	}
+	if _, ok := find(list, "kube_deployment_created", labels); ok {
+		t.Fatal("expected no created series without a creation timestamp")
+	}
+}
+
+func TestPodSeries(t *testing.T) {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "app",
+					RestartCount: 4,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+				},
+			},
+		},
+	}
+	pod := map[string]string{"namespace": "default", "pod": "web-1"}
+	container := map[string]string{"namespace": "default", "pod": "web-1", "container": "app"}
+
+	list := podSeries(p)
+	tests := []struct {
+		name   string
+		labels map[string]string
+		expect float64
+	}{
+		{"kube_pod_status_phase", with(pod, "phase", "Running"), 1},
+		{"kube_pod_status_phase", with(pod, "phase", "Pending"), 0},
+		{"kube_pod_status_ready", with(pod, "condition", "true"), 0},
+		{"kube_pod_status_ready", with(pod, "condition", "false"), 1},
+		{"kube_pod_container_status_restarts_total", container, 4},
+		{"kube_pod_container_status_waiting", container, 1},
+		{"kube_pod_container_status_running", container, 0},
+		{"kube_pod_container_status_waiting_reason", with(container, "reason", "CrashLoopBackOff"), 1},
+	}
+	for _, test := range tests {
+		v, ok := find(list, test.name, test.labels)
+		if !ok {
+			t.Fatalf("%s %v not found", test.name, test.labels)
+		}
+		if v != test.expect {
+			t.Fatalf("%s %v expected %v, got %v", test.name, test.labels, test.expect, v)
+		}
+	}
+}
+
+func TestNamespaceSeries(t *testing.T) {
+	n := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "old"},
+		Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+	}
+	labels := map[string]string{"namespace": "old"}
+
+	list := namespaceSeries(n)
+	if v, _ := find(list, "kube_namespace_status_phase", with(labels, "phase", "Terminating")); v != 1 {
+		t.Fatalf("expected terminating 1, got %v", v)
+	}
+	if v, _ := find(list, "kube_namespace_status_phase", with(labels, "phase", "Active")); v != 0 {
+		t.Fatalf("expected active 0, got %v", v)
+	}
+}
+
+func TestJobSeries(t *testing.T) {
+	j := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "batch", Name: "report"},
+		Status: batchv1.JobStatus{
+			Failed: 2,
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	labels := map[string]string{"namespace": "batch", "job_name": "report"}
+
+	list := jobSeries(j)
+	if v, _ := find(list, "kube_job_status_failed", labels); v != 2 {
+		t.Fatalf("expected failed 2, got %v", v)
+	}
+	if v, _ := find(list, "kube_job_failed", with(labels, "condition", "true")); v != 1 {
+		t.Fatalf("expected failed condition true 1, got %v", v)
+	}
+	if _, ok := find(list, "kube_job_complete", with(labels, "condition", "true")); ok {
+		t.Fatal("expected no complete series without a complete condition")
+	}
+}
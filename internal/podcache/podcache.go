@@ -0,0 +1,319 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package podcache is a cluster wide, watch based, cache of pod
+// metadata shared by the collectors of a cluster
+package podcache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resyncPeriod is how often the informer replays the cached pods,
+// an upper bound on staleness when the watch is quiet
+const resyncPeriod = 5 * time.Minute
+
+// Pod is the cached metadata of a pod, the maps and
+// slices are shared with the cache and must not be modified
+type Pod struct {
+	Namespace       string
+	Name            string
+	UID             string
+	NodeName        string
+	Labels          map[string]string
+	Annotations     map[string]string
+	OwnerReferences []metav1.OwnerReference
+	Containers      []Container
+}
+
+// Container is the resource requests and limits of a pod container
+type Container struct {
+	Name     string
+	Requests corev1.ResourceList
+	Limits   corev1.ResourceList
+}
+
+// Cache is a watch based cache of the pods in a cluster
+type Cache struct {
+	clientset  *kubernetes.Clientset
+	nodeName   string // only pods on this node, blank=all pods
+	check      *circonus.Check
+	log        zerolog.Logger
+	lister     corelisters.PodLister
+	synced     bool
+	lastUpdate time.Time
+	sync.RWMutex
+}
+
+// New returns a new pod cache, it is empty until started. When
+// collecting the local node only pods on that node are cached.
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Cache, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	nodeName := ""
+	if cfg.LocalNode {
+		nodeName = cfg.NodeName
+	}
+	return newCache(cfg, nodeName, parentLog, check)
+}
+
+// NewClusterWide returns a new cache of all pods in the cluster, even
+// when collecting the local node, for the cluster scoped collectors
+func NewClusterWide(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Cache, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	return newCache(cfg, "", parentLog, check)
+}
+
+func newCache(cfg *config.Cluster, nodeName string, parentLog zerolog.Logger, check *circonus.Check) (*Cache, error) {
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	clientset, err := k8s.NewClientset(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "pod cache")
+	}
+
+	return &Cache{
+		clientset: clientset,
+		nodeName:  nodeName,
+		check:     check,
+		log:       parentLog.With().Str("pkg", "podcache").Logger(),
+	}, nil
+}
+
+// Start watches pods until ctx is done, a new watch is
+// established each time the cache is started
+func (c *Cache) Start(ctx context.Context) {
+	defer runtime.HandleCrash()
+
+	var opts []informers.SharedInformerOption
+	if c.nodeName != "" {
+		opts = append(opts, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = "spec.nodeName=" + c.nodeName
+		}))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(c.clientset, resyncPeriod, opts...)
+	podInformer := factory.Core().V1().Pods()
+	informer := podInformer.Informer()
+
+	touch := func() {
+		c.Lock()
+		c.lastUpdate = time.Now()
+		c.Unlock()
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { touch() },
+		UpdateFunc: func(interface{}, interface{}) { touch() },
+		DeleteFunc: func(interface{}) { touch() },
+	})
+
+	c.Lock()
+	c.lister = podInformer.Lister()
+	c.synced = false
+	c.Unlock()
+
+	c.log.Info().Msg("starting pod watch")
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		c.log.Warn().Msg("pod cache did not sync")
+		return
+	}
+
+	c.Lock()
+	c.synced = true
+	c.lastUpdate = time.Now()
+	c.Unlock()
+	c.log.Info().Msg("pod cache synced")
+
+	<-ctx.Done()
+
+	c.Lock()
+	c.synced = false
+	c.Unlock()
+	c.log.Debug().Msg("stopped pod watch")
+}
+
+// Get returns the cached metadata for a pod, false if the pod is
+// not in the cache, the cache has not synced, or the cache is nil
+func (c *Cache) Get(ns, name string) (*Pod, bool) {
+	p, ok := c.Pod(ns, name)
+	if !ok {
+		return nil, false
+	}
+	return podFrom(p), true
+}
+
+// Pod returns a cached pod, false if the pod is not in the cache (see
+// Get), the pod is shared with the cache and must not be modified
+func (c *Cache) Pod(ns, name string) (*corev1.Pod, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	lister, ok := c.syncedLister()
+	if !ok {
+		c.miss("not_synced")
+		return nil, false
+	}
+
+	p, err := lister.Pods(ns).Get(name)
+	if err != nil {
+		c.miss("not_found")
+		return nil, false
+	}
+
+	c.check.IncrementCounter("podcache_hits", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+	})
+
+	return p, true
+}
+
+// Pods returns all cached pods, false if the cache has not synced or
+// is nil, the pods are shared with the cache and must not be modified
+func (c *Cache) Pods() ([]*corev1.Pod, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	lister, ok := c.syncedLister()
+	if !ok {
+		return nil, false
+	}
+
+	pods, err := lister.List(labels.Everything())
+	if err != nil {
+		c.log.Warn().Err(err).Msg("listing cached pods")
+		return nil, false
+	}
+	return pods, true
+}
+
+// syncedLister returns the lister, false if the cache has not synced
+func (c *Cache) syncedLister() (corelisters.PodLister, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.lister == nil || !c.synced {
+		return nil, false
+	}
+	return c.lister, true
+}
+
+// Lookup returns the labels and annotations of a cached pod,
+// false if the pod is not in the cache (see Get)
+func (c *Cache) Lookup(ns, name string) (map[string]string, map[string]string, bool) {
+	p, ok := c.Get(ns, name)
+	if !ok {
+		return nil, nil, false
+	}
+	return p.Labels, p.Annotations, true
+}
+
+// state is the pod cache state emitted by AddMetrics
+type state struct {
+	started   bool // false=cache not started, nothing to emit
+	synced    bool
+	pods      int           // cached pods, when synced
+	staleness time.Duration // since the last update from the watch, -1=no updates
+}
+
+// state returns the current state of the cache
+func (c *Cache) state(now time.Time) state {
+	c.RLock()
+	lister := c.lister
+	synced := c.synced
+	lastUpdate := c.lastUpdate
+	c.RUnlock()
+
+	st := state{staleness: -1}
+	if lister == nil {
+		return st
+	}
+	st.started = true
+	if synced {
+		if pods, err := lister.List(labels.Everything()); err == nil {
+			st.synced = true
+			st.pods = len(pods)
+		}
+	}
+	if !lastUpdate.IsZero() {
+		st.staleness = now.Sub(lastUpdate)
+	}
+	return st
+}
+
+// AddMetrics adds the pod cache state metrics (cached pods,
+// synced, and seconds since the last update from the watch)
+func (c *Cache) AddMetrics(tags cgm.Tags) {
+	st := c.state(time.Now())
+	if !st.started {
+		return
+	}
+
+	s := uint64(0)
+	if st.synced {
+		s = 1
+		c.check.AddGauge("podcache_pods", tags, uint64(st.pods))
+	}
+	c.check.AddGauge("podcache_synced", tags, s)
+
+	if st.staleness >= 0 {
+		var streamTags cgm.Tags
+		streamTags = append(streamTags, tags...)
+		streamTags = append(streamTags, cgm.Tag{Category: "units", Value: "seconds"})
+		c.check.AddGauge("podcache_staleness", streamTags, uint64(st.staleness.Seconds()))
+	}
+}
+
+func (c *Cache) miss(reason string) {
+	c.check.IncrementCounter("podcache_misses", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "reason", Value: reason},
+	})
+}
+
+func podFrom(p *corev1.Pod) *Pod {
+	containers := make([]Container, 0, len(p.Spec.Containers))
+	for _, c := range p.Spec.Containers {
+		containers = append(containers, Container{
+			Name:     c.Name,
+			Requests: c.Resources.Requests,
+			Limits:   c.Resources.Limits,
+		})
+	}
+	return &Pod{
+		Namespace:       p.Namespace,
+		Name:            p.Name,
+		UID:             string(p.UID),
+		NodeName:        p.Spec.NodeName,
+		Labels:          p.Labels,
+		Annotations:     p.Annotations,
+		OwnerReferences: p.OwnerReferences,
+		Containers:      containers,
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package podcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func testPod(ns, name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       ns,
+			Name:            name,
+			UID:             types.UID("uid-" + name),
+			Labels:          map[string]string{"app": name},
+			Annotations:     map[string]string{"circonus.com/collect": "true"},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: name + "-abc"}},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+			Containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+					},
+				},
+				{Name: "sidecar"},
+			},
+		},
+	}
+}
+
+// testCache returns a cache backed by an indexer holding pods
+func testCache(t *testing.T, synced bool, pods ...*corev1.Pod) *Cache {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, p := range pods {
+		if err := indexer.Add(p); err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+	}
+	return &Cache{
+		check:  &circonus.Check{},
+		lister: corelisters.NewPodLister(indexer),
+		synced: synced,
+	}
+}
+
+func TestPodFrom(t *testing.T) {
+	p := podFrom(testPod("default", "web"))
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"namespace", p.Namespace, "default"},
+		{"name", p.Name, "web"},
+		{"uid", p.UID, "uid-web"},
+		{"node", p.NodeName, "node-1"},
+		{"label", p.Labels["app"], "web"},
+		{"annotation", p.Annotations["circonus.com/collect"], "true"},
+		{"owners", len(p.OwnerReferences), 1},
+		{"containers", len(p.Containers), 2},
+		{"container name", p.Containers[0].Name, "app"},
+		{"cpu request", p.Containers[0].Requests.Cpu().MilliValue(), int64(100)},
+		{"memory limit", p.Containers[0].Limits.Memory().Value(), int64(64 * 1024 * 1024)},
+		{"no resources", len(p.Containers[1].Requests) + len(p.Containers[1].Limits), 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name   string
+		cache  *Cache
+		ns     string
+		pod    string
+		wantOK bool
+	}{
+		{"hit", testCache(t, true, testPod("default", "web")), "default", "web", true},
+		{"miss name", testCache(t, true, testPod("default", "web")), "default", "api", false},
+		{"miss namespace", testCache(t, true, testPod("default", "web")), "other", "web", false},
+		{"not synced", testCache(t, false, testPod("default", "web")), "default", "web", false},
+		{"not started", &Cache{check: &circonus.Check{}}, "default", "web", false},
+		{"nil cache", nil, "default", "web", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := tt.cache.Get(tt.ns, tt.pod)
+			if ok != tt.wantOK {
+				t.Fatalf("expected %v, got %v", tt.wantOK, ok)
+			}
+			if ok && (p.Namespace != tt.ns || p.Name != tt.pod) {
+				t.Fatalf("unexpected pod %s/%s", p.Namespace, p.Name)
+			}
+
+			labels, annotations, ok := tt.cache.Lookup(tt.ns, tt.pod)
+			if ok != tt.wantOK {
+				t.Fatalf("lookup expected %v, got %v", tt.wantOK, ok)
+			}
+			if ok && (labels["app"] != tt.pod || annotations["circonus.com/collect"] != "true") {
+				t.Fatalf("unexpected labels %v or annotations %v", labels, annotations)
+			}
+			if !ok && (labels != nil || annotations != nil) {
+				t.Fatal("expected nil labels and annotations on a miss")
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	now := time.Date(2020, 2, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		cache *Cache
+		want  state
+	}{
+		{"not started", &Cache{}, state{staleness: -1}},
+		{"not synced", testCache(t, false, testPod("default", "web")), state{started: true, staleness: -1}},
+		{"synced", testCache(t, true, testPod("default", "web"), testPod("default", "api")), state{started: true, synced: true, pods: 2, staleness: -1}},
+	}
+
+	updated := testCache(t, true, testPod("default", "web"))
+	updated.lastUpdate = now.Add(-90 * time.Second)
+	tests = append(tests, struct {
+		name  string
+		cache *Cache
+		want  state
+	}{"staleness", updated, state{started: true, synced: true, pods: 1, staleness: 90 * time.Second}})
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cache.state(now); got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package prom is the prometheus annotation collector, it discovers
+// pods and services annotated for scraping (prometheus.io/scrape,
+// prometheus.io/port, prometheus.io/path, prometheus.io/scheme) from a
+// watch based cache and scrapes their prometheus metrics concurrently
+package prom
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/promtext"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/registry"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/release"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workload"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resyncPeriod is how often the informers replay the cached objects
+const resyncPeriod = 10 * time.Minute
+
+// Prom is the prometheus annotation collector
+type Prom struct {
+	config        *config.Cluster
+	check         *circonus.Check
+	log           zerolog.Logger
+	clientset     *kubernetes.Clientset
+	annotations   annotations
+	scrapeTimeout time.Duration
+	concurrency   int
+	pods          *podcache.Cache    // shared pod cache, nil=no pod targets
+	filter        *filter.Filter     // nil=all namespaces and pods
+	tags          *tagrules.Rules    // nil=all labels become tags
+	rates         *rates.Store       // nil=no counter rates or deltas
+	workloads     *workload.Resolver // nil=pod controller is the workload
+	listers       *listers           // nil=watch not started or not synced
+	running       bool
+	sync.Mutex
+}
+
+type listers struct {
+	services  corelisters.ServiceLister
+	endpoints corelisters.EndpointsLister
+}
+
+func init() {
+	registry.Register("prometheus", func(env registry.Env) (registry.Collector, error) {
+		p, err := New(env.Config, env.Logger, env.Check)
+		if err != nil {
+			return nil, err
+		}
+		p.pods = env.ClusterPods
+		p.filter = env.Filter
+		p.tags = env.Tags
+		p.rates = env.Rates
+		p.workloads = env.Workloads
+		return p, nil
+	})
+}
+
+// New returns a new prometheus annotation collector, nothing is
+// collected until the watch is started and the cache has synced
+func New(cfg *config.Cluster, parentLog zerolog.Logger, check *circonus.Check) (*Prom, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+
+	clientset, err := k8s.NewClientset(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "prometheus collector")
+	}
+
+	prefix := cfg.PrometheusAnnotationPrefix
+	if prefix == "" {
+		prefix = defaults.K8SPrometheusAnnotationPrefix
+	}
+
+	p := &Prom{
+		config:      cfg,
+		check:       check,
+		log:         parentLog.With().Str("collector", "prometheus").Logger(),
+		clientset:   clientset,
+		annotations: newAnnotations(prefix),
+		concurrency: int(cfg.PrometheusConcurrency),
+	}
+	if p.concurrency == 0 {
+		p.concurrency = int(defaults.K8SPrometheusConcurrency)
+	}
+
+	timeout := cfg.PrometheusScrapeTimeout
+	if timeout == "" {
+		timeout = defaults.K8SPrometheusScrapeTimeout
+	}
+	v, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing prometheus scrape timeout")
+	}
+	p.scrapeTimeout = v
+
+	return p, nil
+}
+
+func (p *Prom) ID() string {
+	return "prometheus"
+}
+
+// Watch watches services and endpoints until ctx is done,
+// pods are taken from the cluster's shared pod cache
+func (p *Prom) Watch(ctx context.Context) {
+	defer runtime.HandleCrash()
+
+	factory := informers.NewSharedInformerFactory(p.clientset, resyncPeriod)
+	services := factory.Core().V1().Services()
+	endpoints := factory.Core().V1().Endpoints()
+	synced := []cache.InformerSynced{
+		services.Informer().HasSynced,
+		endpoints.Informer().HasSynced,
+	}
+
+	l := &listers{
+		services:  services.Lister(),
+		endpoints: endpoints.Lister(),
+	}
+
+	p.log.Info().Msg("starting target watch")
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		p.log.Warn().Msg("target cache did not sync")
+		return
+	}
+
+	p.Lock()
+	p.listers = l
+	p.Unlock()
+	p.log.Info().Msg("target cache synced")
+
+	<-ctx.Done()
+
+	p.Lock()
+	p.listers = nil
+	p.Unlock()
+	p.log.Debug().Msg("stopped target watch")
+}
+
+// Collect scrapes the annotated targets
+func (p *Prom) Collect(ctx context.Context, _ *tls.Config, ts *time.Time) {
+	p.Lock()
+	if p.running {
+		p.log.Warn().Msg("already running")
+		p.Unlock()
+		return
+	}
+	l := p.listers
+	if l == nil {
+		p.log.Warn().Msg("target cache not synced, skipping")
+		p.Unlock()
+		return
+	}
+	p.running = true
+	p.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.log.Error().Interface("panic", r).Msg("recover")
+		}
+		p.Lock()
+		p.running = false
+		p.Unlock()
+	}()
+
+	collectStart := time.Now()
+
+	targets, err := p.targets(l)
+	if err != nil {
+		p.log.Error().Err(err).Msg("discovering targets")
+		return
+	}
+	p.check.AddGauge("collect_prometheus_targets", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+	}, uint64(len(targets)))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, p.concurrency)
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t target) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			p.scrape(ctx, t, ts)
+		}(t)
+	}
+	wg.Wait()
+
+	p.check.AddHistSample("collect_latency", cgm.Tags{
+		cgm.Tag{Category: "source", Value: release.NAME},
+		cgm.Tag{Category: "op", Value: "collect_prometheus"},
+		cgm.Tag{Category: "units", Value: "milliseconds"},
+	}, float64(time.Since(collectStart).Milliseconds()))
+	p.log.Debug().Int("targets", len(targets)).Str("duration", time.Since(collectStart).String()).Msg("prometheus collect end")
+}
+
+// targets returns the annotated pod and service targets, filtered
+// by the cluster namespace and pod filter
+func (p *Prom) targets(l *listers) ([]target, error) {
+	podList, ok := p.pods.Pods()
+	if !ok {
+		return nil, errors.New("pod cache not synced")
+	}
+	pods := make([]*corev1.Pod, 0, len(podList))
+	for _, pod := range podList {
+		if p.filter.Pod(pod.Namespace, pod.Labels, pod.Annotations) {
+			pods = append(pods, pod)
+		}
+	}
+
+	svcList, err := l.services.List(labels.Everything())
+	if err != nil {
+		return nil, errors.Wrap(err, "listing services")
+	}
+	services := make([]*corev1.Service, 0, len(svcList))
+	endpoints := make(map[string]*corev1.Endpoints)
+	for _, svc := range svcList {
+		if !p.filter.Namespace(svc.Namespace) {
+			continue
+		}
+		if _, ok := p.annotations.annotated(svc.Annotations); !ok {
+			continue
+		}
+		eps, err := l.endpoints.Endpoints(svc.Namespace).Get(svc.Name)
+		if err != nil {
+			continue // no endpoints (yet)
+		}
+		services = append(services, svc)
+		endpoints[svc.Namespace+"/"+svc.Name] = eps
+	}
+
+	// endpoints backed by pods excluded by the pod filter are skipped
+	getPod := func(ns, name string) (*corev1.Pod, bool) {
+		pod, ok := p.pods.Pod(ns, name)
+		if !ok {
+			return nil, true
+		}
+		return pod, p.filter.Pod(pod.Namespace, pod.Labels, pod.Annotations)
+	}
+
+	targets := podTargets(p.annotations, pods)
+	targets = append(targets, serviceTargets(p.annotations, services, endpoints, getPod)...)
+
+	return dedupe(targets), nil
+}
+
+// scrape collects the metrics of a target and emits the scrape health
+// metrics (up, scrape_duration_seconds, scrape_samples_scraped)
+func (p *Prom) scrape(ctx context.Context, t target, ts *time.Time) {
+	streamTags := p.targetTags(t)
+
+	start := time.Now()
+	samples, err := p.queueMetrics(ctx, t, streamTags, ts)
+	duration := time.Since(start)
+	if err != nil {
+		p.log.Warn().Err(err).Str("url", t.url).Msg("scraping target")
+	}
+
+	up := 1.0
+	if err != nil {
+		up = 0
+	}
+	metrics := make(map[string]circonus.MetricSample)
+	_ = p.check.QueueMetricSample(metrics, "up", circonus.MetricTypeFloat64, streamTags, nil, up, ts)
+	_ = p.check.QueueMetricSample(metrics, "scrape_duration_seconds", circonus.MetricTypeFloat64, streamTags, nil, duration.Seconds(), ts)
+	_ = p.check.QueueMetricSample(metrics, "scrape_samples_scraped", circonus.MetricTypeUint64, streamTags, nil, samples, ts)
+	if err := p.check.SubmitQueue(ctx, metrics, p.log); err != nil {
+		p.log.Warn().Err(err).Msg("submitting metrics")
+	}
+}
+
+// queueMetrics requests and queues the metrics of a target, returning the
+// number of samples scraped. The scrape timeout applies to each target.
+func (p *Prom) queueMetrics(ctx context.Context, t target, streamTags []string, ts *time.Time) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.scrapeTimeout)
+	defer cancel()
+
+	client, err := k8s.NewAPIClient(nil, p.scrapeTimeout)
+	if err != nil {
+		return 0, errors.Wrap(err, "metrics cli")
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequest("GET", t.url, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "metrics req")
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		p.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "prometheus"},
+		})
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		p.check.IncrementCounter("collect_api_errors", cgm.Tags{
+			cgm.Tag{Category: "source", Value: release.NAME},
+			cgm.Tag{Category: "request", Value: "metrics"},
+			cgm.Tag{Category: "target", Value: "prometheus"},
+			cgm.Tag{Category: "code", Value: fmt.Sprintf("%d", resp.StatusCode)},
+		})
+		return 0, errors.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var samples uint64
+	opts := &promtext.Options{
+		Tags:  p.tags,
+		Rates: p.rates,
+		Filter: func(string, map[string]string) bool {
+			samples++
+			return true
+		},
+	}
+	if err := promtext.QueueMetrics(ctx, p.check, p.log, resp.Body, streamTags, []string{}, ts, opts); err != nil {
+		return samples, errors.Wrap(err, "formatting metrics")
+	}
+	if ctx.Err() != nil {
+		return samples, errors.Wrap(ctx.Err(), "scrape")
+	}
+
+	return samples, nil
+}
+
+// targetTags returns the stream tags of a target
+func (p *Prom) targetTags(t target) []string {
+	tags := []string{
+		"source:prometheus",
+		"namespace:" + t.namespace,
+		"instance:" + t.instance,
+		"__rollup:false", // prevent high cardinality metrics from rolling up
+	}
+	if t.pod != "" {
+		tags = append(tags, "pod:"+t.pod)
+	}
+	if t.service != "" {
+		tags = append(tags, "service:"+t.service)
+	}
+	if kind, name := p.workloads.Resolve(t.namespace, t.owners); kind != "" {
+		if tag, ok := p.tags.Tag("workload_kind", kind); ok {
+			tags = append(tags, tag)
+		}
+		if tag, ok := p.tags.Tag("workload_name", name); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
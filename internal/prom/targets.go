@@ -0,0 +1,190 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package prom
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultPath   = "/metrics"
+	defaultScheme = "http"
+)
+
+// annotations are the names of the scrape annotations for a prefix
+type annotations struct {
+	scrape string // "true" to scrape
+	port   string // blank=first declared container or endpoint port
+	path   string // blank=/metrics
+	scheme string // http|https, blank=http
+}
+
+func newAnnotations(prefix string) annotations {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return annotations{
+		scrape: prefix + "/scrape",
+		port:   prefix + "/port",
+		path:   prefix + "/path",
+		scheme: prefix + "/scheme",
+	}
+}
+
+// target is a discovered metrics endpoint
+type target struct {
+	url       string
+	instance  string // host:port
+	namespace string
+	pod       string                  // blank=not a pod (e.g. endpoint without a pod target ref)
+	service   string                  // blank=discovered from the pod annotations
+	owners    []metav1.OwnerReference // of the pod, for the workload tags
+}
+
+// endpoint is the scheme and path from the annotations of an object
+type endpoint struct {
+	scheme string
+	path   string
+	port   uint64 // 0=not annotated
+}
+
+// annotated returns the scrape endpoint from an object's annotations,
+// false if the object is not annotated for scraping
+func (a annotations) annotated(objAnnotations map[string]string) (endpoint, bool) {
+	if !strings.EqualFold(strings.TrimSpace(objAnnotations[a.scrape]), "true") {
+		return endpoint{}, false
+	}
+
+	ep := endpoint{scheme: defaultScheme, path: defaultPath}
+	if s := strings.ToLower(strings.TrimSpace(objAnnotations[a.scheme])); s == "http" || s == "https" {
+		ep.scheme = s
+	}
+	if p := strings.TrimSpace(objAnnotations[a.path]); p != "" {
+		if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+		ep.path = p
+	}
+	if p, err := strconv.ParseUint(strings.TrimSpace(objAnnotations[a.port]), 10, 16); err == nil && p > 0 {
+		ep.port = p
+	}
+
+	return ep, true
+}
+
+// podTargets returns the targets of running pods annotated for scraping,
+// pods without a port annotation are scraped on their first declared
+// container port and pods without either are skipped
+func podTargets(a annotations, pods []*corev1.Pod) []target {
+	var targets []target
+	for _, p := range pods {
+		ep, ok := a.annotated(p.Annotations)
+		if !ok || p.Status.Phase != corev1.PodRunning || p.Status.PodIP == "" {
+			continue
+		}
+		port := ep.port
+		if port == 0 {
+			port = firstContainerPort(p)
+		}
+		if port == 0 {
+			continue
+		}
+		targets = append(targets, newTarget(ep, p.Status.PodIP, port, p.Namespace, p))
+	}
+	return targets
+}
+
+// serviceTargets returns the targets of the ready endpoint addresses of
+// services annotated for scraping, pods backing the endpoints are looked
+// up with getPod for the pod tags (nil when not found), addresses of pods
+// it returns false for are skipped
+func serviceTargets(a annotations, services []*corev1.Service, endpoints map[string]*corev1.Endpoints, getPod func(ns, name string) (*corev1.Pod, bool)) []target {
+	var targets []target
+	for _, svc := range services {
+		ep, ok := a.annotated(svc.Annotations)
+		if !ok {
+			continue
+		}
+		eps, ok := endpoints[svc.Namespace+"/"+svc.Name]
+		if !ok {
+			continue
+		}
+		for _, subset := range eps.Subsets {
+			port := ep.port
+			if port == 0 && len(subset.Ports) > 0 {
+				port = uint64(subset.Ports[0].Port)
+			}
+			if port == 0 {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				var pod *corev1.Pod
+				if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" && getPod != nil {
+					ns := addr.TargetRef.Namespace
+					if ns == "" {
+						ns = svc.Namespace
+					}
+					var ok bool
+					if pod, ok = getPod(ns, addr.TargetRef.Name); !ok {
+						continue
+					}
+				}
+				t := newTarget(ep, addr.IP, port, svc.Namespace, pod)
+				t.service = svc.Name
+				if pod == nil && addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+					t.pod = addr.TargetRef.Name
+				}
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets
+}
+
+// dedupe removes targets with the same url, the first is kept (pod
+// targets are listed before service targets), and sorts by url
+func dedupe(targets []target) []target {
+	seen := make(map[string]bool, len(targets))
+	out := targets[:0]
+	for _, t := range targets {
+		if seen[t.url] {
+			continue
+		}
+		seen[t.url] = true
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].url < out[j].url })
+	return out
+}
+
+func newTarget(ep endpoint, ip string, port uint64, ns string, pod *corev1.Pod) target {
+	hostPort := net.JoinHostPort(ip, strconv.FormatUint(port, 10))
+	t := target{
+		url:       ep.scheme + "://" + hostPort + ep.path,
+		instance:  hostPort,
+		namespace: ns,
+	}
+	if pod != nil {
+		t.pod = pod.Name
+		t.owners = pod.OwnerReferences
+	}
+	return t
+}
+
+func firstContainerPort(p *corev1.Pod) uint64 {
+	for _, c := range p.Spec.Containers {
+		for _, cp := range c.Ports {
+			if cp.ContainerPort > 0 && (cp.Protocol == "" || cp.Protocol == corev1.ProtocolTCP) {
+				return uint64(cp.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package prom
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnnotated(t *testing.T) {
+	a := newAnnotations("prometheus.io/")
+
+	tests := []struct {
+		desc        string
+		annotations map[string]string
+		ok          bool
+		expect      endpoint
+	}{
+		{"not annotated", map[string]string{}, false, endpoint{}},
+		{"scrape false", map[string]string{"prometheus.io/scrape": "false"}, false, endpoint{}},
+		{"defaults", map[string]string{"prometheus.io/scrape": "true"}, true, endpoint{scheme: "http", path: "/metrics"}},
+		{"all", map[string]string{
+			"prometheus.io/scrape": "True",
+			"prometheus.io/port":   "9102",
+			"prometheus.io/path":   "stats/prometheus",
+			"prometheus.io/scheme": "https",
+		}, true, endpoint{scheme: "https", path: "/stats/prometheus", port: 9102}},
+		{"invalid port and scheme", map[string]string{
+			"prometheus.io/scrape": "true",
+			"prometheus.io/port":   "http",
+			"prometheus.io/scheme": "ftp",
+		}, true, endpoint{scheme: "http", path: "/metrics"}},
+	}
+
+	for _, test := range tests {
+		ep, ok := a.annotated(test.annotations)
+		if ok != test.ok || ep != test.expect {
+			t.Errorf("%s: expected %v %+v, got %v %+v", test.desc, test.ok, test.expect, ok, ep)
+		}
+	}
+}
+
+func TestPodTargets(t *testing.T) {
+	a := newAnnotations("prometheus.io")
+	running := corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"}
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-1", Annotations: map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8080"}},
+			Status:     running,
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "web-2", Annotations: map[string]string{"prometheus.io/scrape": "true"}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Ports: []corev1.ContainerPort{{ContainerPort: 53, Protocol: corev1.ProtocolUDP}, {ContainerPort: 9000}}},
+			}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.2"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "no-port", Annotations: map[string]string{"prometheus.io/scrape": "true"}},
+			Status:     running,
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "pending", Annotations: map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "8080"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "not-annotated"},
+			Status:     running,
+		},
+	}
+
+	targets := podTargets(a, pods)
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].url != "http://10.0.0.1:8080/metrics" || targets[0].pod != "web-1" || targets[0].namespace != "app" {
+		t.Fatalf("unexpected target %+v", targets[0])
+	}
+	if targets[1].url != "http://10.0.0.2:9000/metrics" {
+		t.Fatalf("expected first tcp container port, got %s", targets[1].url)
+	}
+}
+
+func TestServiceTargets(t *testing.T) {
+	a := newAnnotations("prometheus.io")
+	services := []*corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "api", Annotations: map[string]string{"prometheus.io/scrape": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: "no-endpoints", Annotations: map[string]string{"prometheus.io/scrape": "true"}}},
+	}
+	endpoints := map[string]*corev1.Endpoints{
+		"app/api": {
+			Subsets: []corev1.EndpointSubset{{
+				Addresses: []corev1.EndpointAddress{
+					{IP: "10.0.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "api-1"}},
+					{IP: "10.0.0.2", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "api-2"}},
+					{IP: "10.0.0.3", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "excluded"}},
+				},
+				Ports: []corev1.EndpointPort{{Name: "http", Port: 8080}},
+			}},
+		},
+	}
+	getPod := func(ns, name string) (*corev1.Pod, bool) {
+		switch name {
+		case "api-1":
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "api-abc"}}}}, true
+		case "excluded":
+			return &corev1.Pod{}, false
+		default:
+			return nil, true
+		}
+	}
+
+	targets := serviceTargets(a, services, endpoints, getPod)
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	if targets[0].url != "http://10.0.0.1:8080/metrics" || targets[0].service != "api" || len(targets[0].owners) != 1 {
+		t.Fatalf("unexpected target %+v", targets[0])
+	}
+	if targets[1].pod != "api-2" {
+		t.Fatalf("expected pod from target ref when not cached, got %q", targets[1].pod)
+	}
+
+	// a pod annotated itself and behind an annotated service is scraped once
+	all := dedupe(append([]target{{url: "http://10.0.0.1:8080/metrics", pod: "api-1"}}, targets...))
+	if len(all) != 2 || all[0].service != "" {
+		t.Fatalf("expected pod target kept over service target, got %+v", all)
+	}
+}
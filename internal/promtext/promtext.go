@@ -15,6 +15,8 @@ import (
 	"time"
 
 	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/expfmt"
 	"github.com/rs/zerolog"
@@ -28,6 +30,21 @@ const (
 	circCumulativeHistogram = true
 )
 
+// Options are optional settings for QueueMetrics
+type Options struct {
+	// Filter is called with the name and labels of each series,
+	// series it returns false for are not emitted (nil=all series)
+	Filter func(name string, labels map[string]string) bool
+	// Tags are the rules for turning series labels into stream tags (nil=all labels)
+	Tags *tagrules.Rules
+	// ExtraTags is called with the name and labels of each series, the
+	// returned stream tags are added to the series (e.g. pod workload)
+	ExtraTags func(name string, labels map[string]string) []string
+	// Rates derives per second rates or deltas of counters, summary and
+	// histogram counts and sums (nil=only the cumulative values)
+	Rates *rates.Store
+}
+
 // QueueMetrics is a generic function to digest prometheus text format metrics and
 // emit circonus formatted metrics.
 // Formats supported: https://prometheus.io/docs/instrumenting/exposition_formats/
@@ -38,7 +55,8 @@ func QueueMetrics(
 	data io.Reader,
 	parentStreamTags []string,
 	parentMeasurementTags []string,
-	ts *time.Time) error {
+	ts *time.Time,
+	opts *Options) error {
 
 	var baseStreamTags []string
 	if len(parentStreamTags) > 0 {
@@ -46,6 +64,13 @@ func QueueMetrics(
 		copy(baseStreamTags, parentStreamTags)
 	}
 
+	var rules *tagrules.Rules
+	var counters *rates.Store
+	if opts != nil {
+		rules = opts.Tags
+		counters = opts.Rates
+	}
+
 	var parser expfmt.TextParser
 
 	metricFamilies, err := parser.TextToMetricFamilies(data)
@@ -71,8 +96,19 @@ func QueueMetrics(
 				return nil
 			}
 			metricName := mn
-			streamTags := getLabels(m)
+			var seriesLabels map[string]string
+			if opts != nil && (opts.Filter != nil || opts.ExtraTags != nil) {
+				seriesLabels = labelMap(m)
+			}
+			if opts != nil && opts.Filter != nil && !opts.Filter(metricName, seriesLabels) {
+				continue
+			}
+			streamTags := getLabels(m, rules)
 			streamTags = append(streamTags, baseStreamTags...)
+			if opts != nil && opts.ExtraTags != nil {
+				streamTags = append(streamTags, opts.ExtraTags(metricName, seriesLabels)...)
+			}
+			sampleTS := sampleTime(m)
 			switch mf.GetType() {
 			case dto.MetricType_SUMMARY:
 				_ = check.QueueMetricSample(
@@ -80,11 +116,13 @@ func QueueMetrics(
 					circonus.MetricTypeUint64,
 					streamTags, parentMeasurementTags,
 					m.GetSummary().GetSampleCount(), ts)
+				counters.Queue(metrics, metricName+"_count", streamTags, parentMeasurementTags, float64(m.GetSummary().GetSampleCount()), sampleTS, ts)
 				_ = check.QueueMetricSample(
 					metrics, metricName+"_sum",
 					circonus.MetricTypeFloat64,
 					streamTags, parentMeasurementTags,
 					m.GetSummary().GetSampleSum(), ts)
+				counters.Queue(metrics, metricName+"_sum", streamTags, parentMeasurementTags, m.GetSummary().GetSampleSum(), sampleTS, ts)
 				for qn, qv := range getQuantiles(m) {
 					var qtags []string
 					qtags = append(qtags, streamTags...)
@@ -101,11 +139,13 @@ func QueueMetrics(
 					circonus.MetricTypeUint64,
 					streamTags, parentMeasurementTags,
 					m.GetHistogram().GetSampleCount(), ts)
+				counters.Queue(metrics, metricName+"_count", streamTags, parentMeasurementTags, float64(m.GetHistogram().GetSampleCount()), sampleTS, ts)
 				_ = check.QueueMetricSample(
 					metrics, metricName+"_sum",
 					circonus.MetricTypeFloat64,
 					streamTags, parentMeasurementTags,
 					m.GetHistogram().GetSampleSum(), ts)
+				counters.Queue(metrics, metricName+"_sum", streamTags, parentMeasurementTags, m.GetHistogram().GetSampleSum(), sampleTS, ts)
 				if emitHistogramBuckets {
 					if circCumulativeHistogram {
 						var htags []string
@@ -148,6 +188,7 @@ func QueueMetrics(
 							circonus.MetricTypeFloat64,
 							streamTags, parentMeasurementTags,
 							*m.GetCounter().Value, ts)
+						counters.Queue(metrics, metricName, streamTags, parentMeasurementTags, *m.GetCounter().Value, sampleTS, ts)
 					}
 				case m.Untyped != nil:
 					if m.GetUntyped().Value != nil {
@@ -180,6 +221,15 @@ func QueueMetrics(
 	return nil
 }
 
+// sampleTime returns the timestamp of a sample, nil if the exposition did not include one
+func sampleTime(m *dto.Metric) *time.Time {
+	if m.TimestampMs == nil {
+		return nil
+	}
+	ts := time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond))
+	return &ts
+}
+
 // // StreamMetrics is a generic function to digest prometheus text format metrics and
 // // emit circonus formatted metrics.
 // // Formats supported: https://prometheus.io/docs/instrumenting/exposition_formats/
@@ -343,7 +393,7 @@ func QueueMetrics(
 // 	return nil
 // }
 
-func getLabels(m *dto.Metric) []string {
+func getLabels(m *dto.Metric, rules *tagrules.Rules) []string {
 	labels := []string{}
 
 	for _, label := range m.Label {
@@ -354,12 +404,23 @@ func getLabels(m *dto.Metric) []string {
 			continue
 		}
 
-		labels = append(labels, *label.Name+":"+*label.Value)
+		if tag, ok := rules.Tag(*label.Name, *label.Value); ok {
+			labels = append(labels, tag)
+		}
 	}
 
 	return labels
 }
 
+// labelMap returns the labels of a series as a map
+func labelMap(m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.Label))
+	for _, label := range m.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
 func getQuantiles(m *dto.Metric) map[string]float64 {
 	ret := make(map[string]float64)
 	for _, q := range m.GetSummary().Quantile {
@@ -0,0 +1,226 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package rates computes per second rates and deltas of cumulative
+// counters (e.g. cpu seconds, network bytes, prometheus counters),
+// keeping the previous sample of each series between collections
+package rates
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/pkg/errors"
+)
+
+// Mode is how a counter is derived
+type Mode int
+
+const (
+	// None the counter is only emitted as is
+	None Mode = iota
+	// Rate per second rate, emitted as <name>_rate
+	Rate
+	// Delta change since the previous sample, emitted as <name>_delta
+	Delta
+)
+
+// staleAfter is how long a series is kept without a new sample
+const staleAfter = 15 * time.Minute
+
+// Store is the previous sample of each counter series, a nil
+// Store derives nothing
+type Store struct {
+	check  *circonus.Check
+	rates  []*regexp.Regexp
+	deltas []*regexp.Regexp
+	modes  map[string]Mode // decisions by metric name, names are low cardinality
+	series map[string]sample
+	resets uint64
+	sync.Mutex
+}
+
+type sample struct {
+	value float64
+	ts    time.Time // sample timestamp
+	seen  time.Time // last collection the series was seen in
+}
+
+// New returns the counter store for a cluster, nil if no
+// counter rates or deltas are configured
+func New(cfg *config.Cluster, check *circonus.Check) (*Store, error) {
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	s, err := newStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.rates) == 0 && len(s.deltas) == 0 {
+		return nil, nil
+	}
+	s.check = check
+	return s, nil
+}
+
+// newStore parses the counter patterns of the cluster configuration
+func newStore(cfg *config.Cluster) (*Store, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	s := &Store{
+		modes:  make(map[string]Mode),
+		series: make(map[string]sample),
+	}
+
+	var err error
+	if s.rates, err = tagrules.Patterns(cfg.CounterRates); err != nil {
+		return nil, errors.Wrap(err, "counter rates")
+	}
+	if s.deltas, err = tagrules.Patterns(cfg.CounterDeltas); err != nil {
+		return nil, errors.Wrap(err, "counter deltas")
+	}
+
+	return s, nil
+}
+
+// Mode returns how a counter metric is derived, rate patterns
+// take precedence over delta patterns
+func (s *Store) Mode(name string) Mode {
+	if s == nil {
+		return None
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	m, ok := s.modes[name]
+	if !ok {
+		switch {
+		case match(s.rates, name):
+			m = Rate
+		case match(s.deltas, name):
+			m = Delta
+		default:
+			m = None
+		}
+		s.modes[name] = m
+	}
+	return m
+}
+
+// Derive records a counter sample and returns the derived value, false
+// for the first sample of a series or when the sample timestamp has not
+// advanced (e.g. the kubelet has not refreshed its stats). A counter
+// lower than the previous sample was reset and counts from zero.
+func (s *Store) Derive(name string, tags []string, mode Mode, value float64, ts time.Time) (float64, bool) {
+	if s == nil || mode == None {
+		return 0, false
+	}
+
+	key := seriesKey(name, tags)
+
+	s.Lock()
+	defer s.Unlock()
+
+	prev, ok := s.series[key]
+	if ok && !ts.After(prev.ts) {
+		prev.seen = time.Now()
+		s.series[key] = prev
+		return 0, false
+	}
+	s.series[key] = sample{value: value, ts: ts, seen: time.Now()}
+	if !ok {
+		return 0, false
+	}
+
+	delta := value - prev.value
+	if delta < 0 {
+		s.resets++
+		delta = value
+	}
+
+	if mode == Delta {
+		return delta, true
+	}
+	return delta / ts.Sub(prev.ts).Seconds(), true
+}
+
+// Queue derives a counter sample and queues the rate (<name>_rate) or
+// delta (<name>_delta) with the counter's tags. sampleTS is when the
+// counter was sampled (e.g. kubelet stats time), nil uses the current
+// time, ts is the timestamp the derived metric is submitted with.
+func (s *Store) Queue(metrics map[string]circonus.MetricSample, name string, streamTags, measurementTags []string, value float64, sampleTS *time.Time, ts *time.Time) {
+	if s == nil || s.check == nil {
+		return
+	}
+
+	mode := s.Mode(name)
+	if mode == None {
+		return
+	}
+
+	sts := time.Now()
+	if sampleTS != nil && !sampleTS.IsZero() {
+		sts = *sampleTS
+	}
+
+	v, ok := s.Derive(name, streamTags, mode, value, sts)
+	if !ok {
+		return
+	}
+
+	suffix := "_rate"
+	if mode == Delta {
+		suffix = "_delta"
+	}
+	_ = s.check.QueueMetricSample(metrics, name+suffix, circonus.MetricTypeFloat64, streamTags, measurementTags, v, ts)
+}
+
+// AddMetrics removes stale series and adds the number of series
+// tracked and counter resets seen
+func (s *Store) AddMetrics(tags cgm.Tags) {
+	if s == nil || s.check == nil {
+		return
+	}
+
+	s.Lock()
+	for key, smp := range s.series {
+		if time.Since(smp.seen) > staleAfter {
+			delete(s.series, key)
+		}
+	}
+	series := len(s.series)
+	resets := s.resets
+	s.Unlock()
+
+	s.check.AddGauge("collect_counter_series", tags, uint64(series))
+	s.check.SetCounter("collect_counter_resets", tags, resets)
+}
+
+// seriesKey identifies a series by name and (order independent) tags
+func seriesKey(name string, tags []string) string {
+	sorted := make([]string, len(tags))
+	copy(sorted, tags)
+	sort.Strings(sorted)
+	return name + "|" + strings.Join(sorted, ",")
+}
+
+func match(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
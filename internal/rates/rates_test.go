@@ -0,0 +1,91 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package rates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestMode(t *testing.T) {
+	s, err := newStore(&config.Cluster{CounterRates: "usageCoreNanoSeconds,/_seconds_total$/", CounterDeltas: "rx,tx,*_seconds_total"})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	tests := map[string]Mode{
+		"usageCoreNanoSeconds":               Rate,
+		"container_cpu_usage_seconds_total":  Rate, // rates take precedence
+		"rx":                                 Delta,
+		"usageNanoCores":                     None,
+		"container_memory_working_set_bytes": None,
+	}
+
+	for name, want := range tests {
+		if got := s.Mode(name); got != want {
+			t.Fatalf("%s: expected %d, got %d", name, want, got)
+		}
+	}
+
+	t.Log("invalid pattern")
+	if _, err := newStore(&config.Cluster{CounterRates: "/[/"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	s, err := newStore(&config.Cluster{})
+	if err != nil {
+		t.Fatalf("unexpected error (%s)", err)
+	}
+
+	t0 := time.Unix(1600000000, 0)
+	tags := []string{"pod:web", "namespace:default"}
+
+	tests := []struct {
+		name  string
+		mode  Mode
+		value float64
+		ts    time.Time
+		want  float64
+		ok    bool
+	}{
+		{"baseline", Rate, 100, t0, 0, false},
+		{"rate", Rate, 400, t0.Add(10 * time.Second), 30, true},
+		{"same sample", Rate, 400, t0.Add(10 * time.Second), 0, false},
+		{"reset", Rate, 50, t0.Add(20 * time.Second), 5, true},
+		{"delta", Delta, 80, t0.Add(30 * time.Second), 30, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := s.Derive("usageCoreNanoSeconds", tags, tt.mode, tt.value, tt.ts)
+		if ok != tt.ok || got != tt.want {
+			t.Fatalf("%s: expected %v %v, got %v %v", tt.name, tt.want, tt.ok, got, ok)
+		}
+	}
+
+	if s.resets != 1 {
+		t.Fatalf("expected 1 reset, got %d", s.resets)
+	}
+
+	t.Log("tag order")
+	if _, ok := s.Derive("usageCoreNanoSeconds", []string{"namespace:default", "pod:web"}, Delta, 90, t0.Add(40*time.Second)); !ok {
+		t.Fatal("expected existing series")
+	}
+
+	t.Log("nil store")
+	{
+		var ns *Store
+		if _, ok := ns.Derive("rx", nil, Rate, 1, t0); ok {
+			t.Fatal("expected nothing derived")
+		}
+		if ns.Mode("rx") != None {
+			t.Fatal("expected none")
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package registry is the collector registry. Collector packages
+// register a factory by name (in init) and clusters create the
+// collectors listed in their configuration.
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/filter"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/podcache"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/rates"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/shard"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/tagrules"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/workload"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// Collector is implemented by all collectors
+type Collector interface {
+	ID() string
+}
+
+// Periodic collectors are run by the cluster on a schedule
+type Periodic interface {
+	Collector
+	Collect(context.Context, *tls.Config, *time.Time)
+}
+
+// Streaming collectors are started once and run until ctx is done
+type Streaming interface {
+	Collector
+	Start(context.Context, *tls.Config)
+}
+
+// Watching periodic collectors collect from a watch based cache, the
+// watch is run like a streaming collector (started once, until ctx is done)
+type Watching interface {
+	Periodic
+	Watch(context.Context)
+}
+
+// Scope is what a collector collects from, used when sharding
+type Scope string
+
+const (
+	// ScopeCluster collectors run on the primary shard only (default)
+	ScopeCluster Scope = "cluster"
+	// ScopeNode collectors run on every shard, collecting from the nodes the shard owns
+	ScopeNode Scope = "node"
+)
+
+// Scoped collectors declare their scope, collectors which
+// do not implement Scoped are cluster scoped
+type Scoped interface {
+	Scope() Scope
+}
+
+// ScopeOf returns the scope of a collector
+func ScopeOf(c Collector) Scope {
+	if sc, ok := c.(Scoped); ok {
+		return sc.Scope()
+	}
+	return ScopeCluster
+}
+
+// Env is passed to a collector factory
+type Env struct {
+	Config      *config.Cluster    // cluster configuration
+	Options     map[string]string  // collector specific options from the cluster configuration
+	Logger      zerolog.Logger     // cluster logger
+	Check       *circonus.Check    // cluster check
+	Shard       *shard.Shard       // shard membership (nil when sharding is disabled)
+	Pods        *podcache.Cache    // shared pod metadata cache, only the local node's pods when collecting the local node (nil when not used)
+	ClusterPods *podcache.Cache    // shared cache of all pods for cluster scoped collectors, same as Pods unless collecting the local node (nil when not used)
+	Filter      *filter.Filter     // namespace and pod filter (nil collects everything)
+	Tags        *tagrules.Rules    // label to tag rules (nil turns every label into a tag)
+	Workloads   *workload.Resolver // pod workload resolver (nil when not used)
+	Rates       *rates.Store       // counter rates and deltas (nil derives nothing)
+}
+
+// Factory creates a collector, the returned collector must
+// implement Periodic or Streaming
+type Factory func(env Env) (Collector, error)
+
+var (
+	factories   = make(map[string]Factory)
+	factoriesmu sync.RWMutex
+)
+
+// Register makes a collector factory available by name, it
+// panics if called twice with the same name or a nil factory
+func Register(name string, factory Factory) {
+	factoriesmu.Lock()
+	defer factoriesmu.Unlock()
+
+	if factory == nil {
+		panic("registry: register nil factory for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("registry: register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Lookup returns the factory registered with name
+func Lookup(name string) (Factory, bool) {
+	factoriesmu.RLock()
+	defer factoriesmu.RUnlock()
+
+	f, ok := factories[name]
+	return f, ok
+}
+
+// Names returns a sorted list of the registered collector names
+func Names() []string {
+	factoriesmu.RLock()
+	defer factoriesmu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the collector registered with name
+func New(name string, env Env) (Collector, error) {
+	f, ok := Lookup(name)
+	if !ok {
+		return nil, errors.Errorf("unknown collector (%s), registered: %v", name, Names())
+	}
+
+	c, err := f(env)
+	if err != nil {
+		return nil, errors.Wrapf(err, "initializing %s collector", name)
+	}
+
+	switch c.(type) {
+	case Periodic, Streaming:
+	default:
+		return nil, errors.Errorf("invalid collector (%s), must be periodic or streaming", name)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+type periodic struct{}
+
+func (p *periodic) ID() string                                       { return "test_periodic" }
+func (p *periodic) Collect(context.Context, *tls.Config, *time.Time) {}
+
+type invalid struct{}
+
+func (i *invalid) ID() string { return "test_invalid" }
+
+func TestRegistry(t *testing.T) {
+	t.Log("Testing Register/New")
+
+	Register("test_periodic", func(env Env) (Collector, error) { return &periodic{}, nil })
+	Register("test_invalid", func(env Env) (Collector, error) { return &invalid{}, nil })
+
+	t.Log("registered")
+	if _, err := New("test_periodic", Env{}); err != nil {
+		t.Fatalf("expected no error, got (%s)", err)
+	}
+
+	t.Log("unknown")
+	if _, err := New("test_unknown", Env{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	t.Log("not periodic or streaming")
+	if _, err := New("test_invalid", Env{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	t.Log("duplicate")
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	Register("test_periodic", func(env Env) (Collector, error) { return &periodic{}, nil })
+}
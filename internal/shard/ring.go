@@ -0,0 +1,71 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package shard
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// replicaPoints is the number of points each member has on the ring,
+// more points give a more even distribution of keys across members
+const replicaPoints = 128
+
+// Ring is a consistent hash ring, when a member is added or removed
+// only the keys owned by that member move
+type Ring struct {
+	points  []uint32
+	owners  map[uint32]string
+	members []string
+}
+
+// NewRing returns a ring for the members
+func NewRing(members []string) *Ring {
+	r := &Ring{
+		owners:  make(map[uint32]string, len(members)*replicaPoints),
+		members: append([]string{}, members...),
+	}
+	sort.Strings(r.members)
+
+	for _, m := range r.members {
+		for i := 0; i < replicaPoints; i++ {
+			h := hash(m + "#" + strconv.Itoa(i))
+			if _, dup := r.owners[h]; dup {
+				continue
+			}
+			r.owners[h] = m
+			r.points = append(r.points, h)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+
+	return r
+}
+
+// Owner returns the member which owns key, blank if the ring is empty
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hash(key)
+	idx := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if idx == len(r.points) {
+		idx = 0
+	}
+	return r.owners[r.points[idx]]
+}
+
+// Members returns the sorted ring members
+func (r *Ring) Members() []string {
+	return append([]string{}, r.members...)
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
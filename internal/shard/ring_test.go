@@ -0,0 +1,60 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package shard
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	t.Log("Testing Ring")
+
+	var nodes []string
+	for i := 0; i < 2000; i++ {
+		nodes = append(nodes, fmt.Sprintf("node-%04d", i))
+	}
+
+	t.Log("empty")
+	if o := NewRing(nil).Owner("node-0000"); o != "" {
+		t.Fatalf("expected blank owner, got (%s)", o)
+	}
+
+	members := []string{"agent-0", "agent-1", "agent-2", "agent-3"}
+	r := NewRing(members)
+
+	t.Log("every key owned, distribution")
+	owned := make(map[string]int)
+	for _, n := range nodes {
+		o := r.Owner(n)
+		if o == "" {
+			t.Fatalf("expected owner for %s", n)
+		}
+		owned[o]++
+	}
+	for _, m := range members {
+		if owned[m] < len(nodes)/len(members)/2 {
+			t.Fatalf("uneven distribution %v", owned)
+		}
+	}
+
+	t.Log("stable, member order does not matter")
+	r2 := NewRing([]string{"agent-3", "agent-1", "agent-0", "agent-2"})
+	for _, n := range nodes {
+		if r.Owner(n) != r2.Owner(n) {
+			t.Fatalf("expected same owner for %s", n)
+		}
+	}
+
+	t.Log("adding a member only moves keys to the new member")
+	r3 := NewRing(append(members, "agent-4"))
+	for _, n := range nodes {
+		before, after := r.Owner(n), r3.Owner(n)
+		if before != after && after != "agent-4" {
+			t.Fatalf("%s moved from %s to %s", n, before, after)
+		}
+	}
+}
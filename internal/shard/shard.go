@@ -0,0 +1,326 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package shard splits node collection across agent replicas. Each
+// replica is a member of a shard group (via Leases or a StatefulSet
+// ordinal) and nodes are assigned to members with a consistent hash.
+package shard
+
+import (
+	"context"
+	"expvar"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config/defaults"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// MembershipLease each replica maintains a lease, live leases are members
+	MembershipLease = "lease"
+	// MembershipStatefulSet each replica is a statefulset pod, the ordinal is the member
+	MembershipStatefulSet = "statefulset"
+
+	// groupLabel is the label used to find the member leases of a group
+	groupLabel = "circonus.com/shard-group"
+)
+
+// shardStats exposes the shard membership of each cluster in /stats
+var shardStats = expvar.NewMap("sharding")
+
+// Info is the current state of the shard
+type Info struct {
+	Identity   string   `json:"identity"`
+	Member     string   `json:"member"`
+	Membership string   `json:"membership"`
+	Group      string   `json:"group"`
+	Namespace  string   `json:"namespace"`
+	Members    []string `json:"members"`
+	Primary    bool     `json:"primary"`
+}
+
+// Shard is the membership of this replica in a shard group
+type Shard struct {
+	clientset  *kubernetes.Clientset
+	cfg        *config.Cluster
+	log        zerolog.Logger
+	identity   string // hostname (pod name)
+	self       string // member name on the ring (identity or statefulset ordinal)
+	membership string
+	group      string
+	namespace  string
+	ring       *Ring
+	sync.RWMutex
+}
+
+// New returns the shard membership for this replica
+func New(cfg *config.Cluster, parentLog zerolog.Logger) (*Shard, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	s := &Shard{
+		cfg:        cfg,
+		log:        parentLog.With().Str("pkg", "shard").Logger(),
+		membership: cfg.ShardMembership,
+		group:      cfg.ShardGroup,
+		namespace:  cfg.ShardNS,
+		ring:       NewRing(nil),
+	}
+
+	if s.membership == "" {
+		s.membership = defaults.K8SShardMembership
+	}
+	if s.group == "" {
+		s.group = defaults.K8SShardGroup
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrap(err, "shard identity")
+	}
+	s.identity = identity
+
+	switch s.membership {
+	case MembershipLease:
+		s.self = identity
+	case MembershipStatefulSet:
+		ordinal, err := ordinal(identity)
+		if err != nil {
+			return nil, err
+		}
+		s.self = strconv.Itoa(ordinal)
+	default:
+		return nil, errors.Errorf("invalid shard membership (%s)", s.membership)
+	}
+
+	if s.namespace == "" {
+		ns, err := k8s.AgentNamespace()
+		if err != nil {
+			return nil, errors.Wrap(err, "shard namespace")
+		}
+		s.namespace = ns
+	}
+
+	clientset, err := k8s.NewClientset(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "shard membership")
+	}
+	s.clientset = clientset
+
+	s.log.Debug().
+		Str("identity", s.identity).
+		Str("member", s.self).
+		Str("membership", s.membership).
+		Str("group", s.namespace+"/"+s.group).
+		Msg("using sharding")
+
+	return s, nil
+}
+
+// Start maintains the shard membership until ctx is done, the membership
+// is in /stats while running and removed when the cluster stops
+func (s *Shard) Start(ctx context.Context) {
+	shardStats.Set(s.cfg.Name, expvar.Func(func() interface{} {
+		return s.Info()
+	}))
+	defer shardStats.Delete(s.cfg.Name)
+
+	s.refresh()
+
+	ticker := time.NewTicker(defaults.K8SShardRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if s.membership == MembershipLease {
+				// leave the group so the remaining members take over without waiting for the lease to expire
+				if err := s.clientset.CoordinationV1().Leases(s.namespace).Delete(s.leaseName(), &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+					s.log.Warn().Err(err).Msg("deleting shard lease")
+				}
+			}
+			return
+		case <-ticker.C:
+			s.refresh()
+		}
+	}
+}
+
+// Owns returns whether key (e.g. a node name) is assigned to this replica
+func (s *Shard) Owns(key string) bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.ring.Owner(key) == s.self
+}
+
+// Primary returns whether this replica is the primary member of the
+// group (the first member), cluster level collectors only run on the primary
+func (s *Shard) Primary() bool {
+	s.RLock()
+	defer s.RUnlock()
+	members := s.ring.Members()
+	return len(members) > 0 && members[0] == s.self
+}
+
+// Identity returns the identity of this replica
+func (s *Shard) Identity() string {
+	return s.identity
+}
+
+// Info returns the current state of the shard
+func (s *Shard) Info() Info {
+	s.RLock()
+	members := s.ring.Members()
+	s.RUnlock()
+	return Info{
+		Identity:   s.identity,
+		Member:     s.self,
+		Membership: s.membership,
+		Group:      s.group,
+		Namespace:  s.namespace,
+		Members:    members,
+		Primary:    len(members) > 0 && members[0] == s.self,
+	}
+}
+
+// refresh updates the current members, on error the previous members are kept
+func (s *Shard) refresh() {
+	var members []string
+	var err error
+
+	switch s.membership {
+	case MembershipLease:
+		members, err = s.leaseMembers()
+	case MembershipStatefulSet:
+		members, err = s.statefulSetMembers()
+	}
+	if err != nil {
+		s.log.Warn().Err(err).Msg("refreshing shard members, using previous members")
+		return
+	}
+
+	sort.Strings(members)
+
+	s.Lock()
+	changed := strings.Join(members, ",") != strings.Join(s.ring.Members(), ",")
+	if changed {
+		s.ring = NewRing(members)
+	}
+	s.Unlock()
+
+	if changed {
+		s.log.Info().Strs("members", members).Bool("primary", s.Primary()).Msg("shard membership changed")
+	}
+}
+
+// leaseMembers renews the lease for this replica and returns the
+// identities of the replicas holding a current lease in the group
+func (s *Shard) leaseMembers() ([]string, error) {
+	leases := s.clientset.CoordinationV1().Leases(s.namespace)
+	now := metav1.NewMicroTime(time.Now())
+	duration := int32(defaults.K8SLeaseDuration.Seconds())
+
+	lease, err := leases.Get(s.leaseName(), metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.namespace,
+				Labels:    map[string]string{groupLabel: s.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &s.identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if _, err := leases.Create(lease); err != nil {
+			return nil, errors.Wrap(err, "creating shard lease")
+		}
+	case err != nil:
+		return nil, errors.Wrap(err, "getting shard lease")
+	default:
+		lease.Spec.HolderIdentity = &s.identity
+		lease.Spec.LeaseDurationSeconds = &duration
+		lease.Spec.RenewTime = &now
+		if _, err := leases.Update(lease); err != nil {
+			return nil, errors.Wrap(err, "renewing shard lease")
+		}
+	}
+
+	list, err := leases.List(metav1.ListOptions{LabelSelector: groupLabel + "=" + s.group})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing shard leases")
+	}
+
+	var members []string
+	for _, l := range list.Items {
+		if l.Spec.HolderIdentity == nil || l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expires := l.Spec.RenewTime.Add(time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second)
+		if expires.Before(now.Time) {
+			continue // replica went away without deleting its lease
+		}
+		members = append(members, *l.Spec.HolderIdentity)
+	}
+
+	return members, nil
+}
+
+// statefulSetMembers returns the ordinals of the statefulset replicas
+func (s *Shard) statefulSetMembers() ([]string, error) {
+	replicas := int(s.cfg.ShardReplicas)
+	if replicas == 0 {
+		ss, err := s.clientset.AppsV1().StatefulSets(s.namespace).Get(s.group, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "getting statefulset")
+		}
+		if ss.Spec.Replicas != nil {
+			replicas = int(*ss.Spec.Replicas)
+		}
+	}
+	if replicas < 1 {
+		return nil, errors.Errorf("invalid statefulset replicas (%d)", replicas)
+	}
+
+	members := make([]string, replicas)
+	for i := 0; i < replicas; i++ {
+		members[i] = strconv.Itoa(i)
+	}
+	return members, nil
+}
+
+func (s *Shard) leaseName() string {
+	return s.group + "-" + s.identity
+}
+
+// ordinal returns the ordinal of a statefulset pod name (e.g. agent-2 is 2)
+func ordinal(podName string) (int, error) {
+	idx := strings.LastIndex(podName, "-")
+	if idx == -1 || idx == len(podName)-1 {
+		return 0, errors.Errorf("invalid statefulset pod name (%s), no ordinal", podName)
+	}
+	n, err := strconv.Atoi(podName[idx+1:])
+	if err != nil || n < 0 {
+		return 0, errors.Errorf("invalid statefulset pod name (%s), no ordinal", podName)
+	}
+	return n, nil
+}
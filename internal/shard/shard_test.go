@@ -0,0 +1,44 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package shard
+
+import "testing"
+
+func TestOrdinal(t *testing.T) {
+	t.Log("Testing ordinal")
+
+	tests := []struct {
+		name       string
+		pod        string
+		expect     int
+		shouldFail bool
+	}{
+		{"valid", "circonus-kubernetes-agent-2", 2, false},
+		{"valid zero", "agent-0", 0, false},
+		{"no ordinal", "agent", 0, true},
+		{"trailing dash", "agent-", 0, true},
+		{"not a number", "agent-abc12", 0, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			n, err := ordinal(test.pod)
+			if test.shouldFail {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got (%s)", err)
+			}
+			if n != test.expect {
+				t.Fatalf("expected %d, got %d", test.expect, n)
+			}
+		})
+	}
+}
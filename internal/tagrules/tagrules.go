@@ -0,0 +1,239 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package tagrules controls which kubernetes and prometheus labels
+// become stream tags (allow and deny patterns) and renames label keys
+package tagrules
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	cgm "github.com/circonus-labs/circonus-gometrics/v3"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/circonus"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/pkg/errors"
+)
+
+// Rules are the label to tag rules for a cluster, a nil Rules
+// turns every label into a tag
+type Rules struct {
+	check     *circonus.Check
+	allow     []*regexp.Regexp // empty=all labels
+	deny      []*regexp.Regexp
+	rename    map[string]string
+	decisions map[string]decision // label key decisions, keys are low cardinality
+	dropped   map[string]uint64   // dropped labels by key
+	sync.Mutex
+}
+
+type decision struct {
+	key  string
+	keep bool
+}
+
+// New returns the label to tag rules for a cluster
+func New(cfg *config.Cluster, check *circonus.Check) (*Rules, error) {
+	if check == nil {
+		return nil, errors.New("invalid check (nil)")
+	}
+	r, err := newRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.check = check
+	return r, nil
+}
+
+// newRules parses the tag rules of the cluster configuration
+func newRules(cfg *config.Cluster) (*Rules, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	r := &Rules{
+		rename:    make(map[string]string),
+		decisions: make(map[string]decision),
+		dropped:   make(map[string]uint64),
+	}
+
+	var err error
+	if r.allow, err = Patterns(cfg.TagAllow); err != nil {
+		return nil, errors.Wrap(err, "tag allow")
+	}
+	if r.deny, err = Patterns(cfg.TagDeny); err != nil {
+		return nil, errors.Wrap(err, "tag deny")
+	}
+
+	for _, rule := range Split(cfg.TagRename) {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, errors.Errorf("invalid tag rename rule (%s), expected from=to", rule)
+		}
+		r.rename[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return r, nil
+}
+
+// Key returns the tag category for a label key, false if the label
+// is dropped. Allow and deny patterns match the original label key,
+// rename rules are applied to allowed keys.
+func (r *Rules) Key(key string) (string, bool) {
+	if r == nil {
+		return key, true
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	d, ok := r.decisions[key]
+	if !ok {
+		d = decision{key: key, keep: r.keep(key)}
+		if to, found := r.rename[key]; found {
+			d.key = to
+		}
+		r.decisions[key] = d
+	}
+	if !d.keep {
+		r.dropped[key]++
+	}
+
+	return d.key, d.keep
+}
+
+// Tag returns the stream tag (key:value) for a label, false if the label is dropped
+func (r *Rules) Tag(key, value string) (string, bool) {
+	k, ok := r.Key(key)
+	if !ok {
+		return "", false
+	}
+	return k + ":" + value, true
+}
+
+// Tags returns the stream tags for a set of labels
+func (r *Rules) Tags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if tag, ok := r.Tag(k, v); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// AddMetrics adds the count of dropped labels by label key
+func (r *Rules) AddMetrics(tags cgm.Tags) {
+	if r == nil || r.check == nil {
+		return
+	}
+
+	r.Lock()
+	dropped := make(map[string]uint64, len(r.dropped))
+	for k, v := range r.dropped {
+		dropped[k] = v
+	}
+	r.Unlock()
+
+	for k, v := range dropped {
+		var streamTags cgm.Tags
+		streamTags = append(streamTags, tags...)
+		streamTags = append(streamTags, cgm.Tag{Category: "label", Value: k})
+		r.check.SetCounter("collect_tags_dropped", streamTags, v)
+	}
+}
+
+func (r *Rules) keep(key string) bool {
+	if len(r.allow) > 0 && !match(r.allow, key) {
+		return false
+	}
+	return !match(r.deny, key)
+}
+
+func match(res []*regexp.Regexp, key string) bool {
+	for _, re := range res {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Patterns parses a comma separated list of patterns, a pattern
+// enclosed in slashes is a regular expression (e.g. /^app\..*/),
+// otherwise it is a glob where * matches any characters and ? one
+func Patterns(list string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range Split(list) {
+		expr := ""
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			expr = p[1 : len(p)-1]
+		} else {
+			expr = regexp.QuoteMeta(p)
+			expr = strings.ReplaceAll(expr, `\*`, ".*")
+			expr = strings.ReplaceAll(expr, `\?`, ".")
+			expr = "^" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern (%s)", p)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Split splits a comma separated list into trimmed, non-empty items. A
+// regular expression enclosed in slashes, at the start of an item or after
+// an "=" (e.g. reason=/^(A|B){1,2}$/), may contain commas, it ends at a
+// slash followed by a comma or the end of the list.
+func Split(list string) []string {
+	var items []string
+	add := func(item string) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	start := 0
+	patternStart := true // next non-space character starts a pattern
+	inRegex := false
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		if inRegex {
+			switch {
+			case c == '\\':
+				i++ // escaped character, e.g. \/
+			case c == '/' && regexEnd(list[i+1:]):
+				inRegex = false
+			}
+			continue
+		}
+		switch c {
+		case ',':
+			add(list[start:i])
+			start = i + 1
+			patternStart = true
+		case '=':
+			patternStart = true
+		case ' ', '\t':
+		case '/':
+			inRegex = patternStart
+			patternStart = false
+		default:
+			patternStart = false
+		}
+	}
+	add(list[start:])
+
+	return items
+}
+
+// regexEnd returns whether a slash followed by rest closes a regular expression
+func regexEnd(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == ','
+}
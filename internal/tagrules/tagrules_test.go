@@ -0,0 +1,111 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package tagrules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+)
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Cluster
+		key   string
+		want  string
+		found bool
+	}{
+		{"no rules", config.Cluster{}, "pod-template-hash", "pod-template-hash:v", true},
+		{"deny glob", config.Cluster{TagDeny: "*-hash"}, "pod-template-hash", "", false},
+		{"deny exact", config.Cluster{TagDeny: "controller-revision-hash"}, "app", "app:v", true},
+		{"allow glob", config.Cluster{TagAllow: "app.kubernetes.io/*"}, "app.kubernetes.io/name", "app.kubernetes.io/name:v", true},
+		{"not allowed", config.Cluster{TagAllow: "app.kubernetes.io/*"}, "tier", "", false},
+		{"allow regex", config.Cluster{TagAllow: `/^(app|tier)$/`}, "tier", "tier:v", true},
+		{"deny wins", config.Cluster{TagAllow: "*", TagDeny: "tier"}, "tier", "", false},
+		{"rename", config.Cluster{TagRename: "app.kubernetes.io/name=app"}, "app.kubernetes.io/name", "app:v", true},
+		{"rename matches original", config.Cluster{TagAllow: "app.kubernetes.io/name", TagRename: "app.kubernetes.io/name=app"}, "app.kubernetes.io/name", "app:v", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newRules(&tt.cfg)
+			if err != nil {
+				t.Fatalf("unexpected error (%s)", err)
+			}
+			tag, ok := r.Tag(tt.key, "v")
+			if ok != tt.found {
+				t.Fatalf("expected %v, got %v", tt.found, ok)
+			}
+			if tag != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, tag)
+			}
+			if !ok && r.dropped[tt.key] != 1 {
+				t.Fatalf("expected 1 dropped, got %d", r.dropped[tt.key])
+			}
+		})
+	}
+
+	t.Log("invalid rename")
+	{
+		if _, err := newRules(&config.Cluster{TagRename: "app"}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("invalid regex")
+	{
+		if _, err := newRules(&config.Cluster{TagDeny: "/(/"}); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	t.Log("nil rules")
+	{
+		var r *Rules
+		if tag, ok := r.Tag("app", "v"); !ok || tag != "app:v" {
+			t.Fatalf("expected app:v, got %q", tag)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{" a , ,b ", []string{"a", "b"}},
+		{"app.kubernetes.io/*,tier", []string{"app.kubernetes.io/*", "tier"}},
+		{"/^a{1,2}$/,b", []string{"/^a{1,2}$/", "b"}},
+		{"a, /x,y/ ,b", []string{"a", "/x,y/", "b"}},
+		{`/a\/,b/`, []string{`/a\/,b/`}},
+		{"/a/b,c/", []string{"/a/b,c/"}},
+		{"type=Warning,reason=/^(A|B){1,2}$/", []string{"type=Warning", "reason=/^(A|B){1,2}$/"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.list, func(t *testing.T) {
+			if got := Split(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Log("regex with a comma")
+	{
+		res, err := Patterns("/^a{1,2}$/")
+		if err != nil {
+			t.Fatalf("unexpected error (%s)", err)
+		}
+		if len(res) != 1 || !res[0].MatchString("aa") {
+			t.Fatalf("expected one pattern matching aa, got %v", res)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+// Package workload resolves the workload (Deployment, StatefulSet,
+// DaemonSet, Job, CronJob, etc.) which owns a pod, following the owner
+// references of ReplicaSets and Jobs from a watch based cache
+package workload
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/config"
+	"github.com/circonus-labs/circonus-kubernetes-agent/internal/k8s"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resyncPeriod is how often the informers replay the cached objects
+const resyncPeriod = 10 * time.Minute
+
+// Resolver resolves the workload owning a pod
+type Resolver struct {
+	clientset   *kubernetes.Clientset
+	log         zerolog.Logger
+	replicaSets appslisters.ReplicaSetLister
+	jobs        batchlisters.JobLister
+	synced      bool
+	sync.RWMutex
+}
+
+// New returns a new workload resolver, owners are not followed
+// past the pod's controller until the resolver is started
+func New(cfg *config.Cluster, parentLog zerolog.Logger) (*Resolver, error) {
+	if cfg == nil {
+		return nil, errors.New("invalid cluster config (nil)")
+	}
+
+	clientset, err := k8s.NewClientset(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "workload resolver")
+	}
+
+	return &Resolver{
+		clientset: clientset,
+		log:       parentLog.With().Str("pkg", "workload").Logger(),
+	}, nil
+}
+
+// Start watches ReplicaSets and Jobs until ctx is done. The informers
+// use their own (unfiltered) factory, owners are in the pod's namespace
+// regardless of which pods are collected.
+func (r *Resolver) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	defer runtime.HandleCrash()
+
+	factory := informers.NewSharedInformerFactory(r.clientset, resyncPeriod)
+	rsInformer := factory.Apps().V1().ReplicaSets()
+	jobInformer := factory.Batch().V1().Jobs()
+	synced := []cache.InformerSynced{
+		rsInformer.Informer().HasSynced,
+		jobInformer.Informer().HasSynced,
+	}
+
+	r.Lock()
+	r.replicaSets = rsInformer.Lister()
+	r.jobs = jobInformer.Lister()
+	r.synced = false
+	r.Unlock()
+
+	r.log.Info().Msg("starting workload watch")
+	factory.Start(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		r.log.Warn().Msg("workload cache did not sync")
+		return
+	}
+
+	r.Lock()
+	r.synced = true
+	r.Unlock()
+	r.log.Info().Msg("workload cache synced")
+
+	<-ctx.Done()
+
+	r.Lock()
+	r.synced = false
+	r.Unlock()
+	r.log.Debug().Msg("stopped workload watch")
+}
+
+// Resolve returns the kind and name of the workload owning a pod in
+// namespace ns. The pod's controller is followed from a ReplicaSet to
+// its Deployment and from a Job to its CronJob, other controllers are
+// the workload. Blank if the pod has no controller.
+func (r *Resolver) Resolve(ns string, owners []metav1.OwnerReference) (string, string) {
+	ref := controllerRef(owners)
+	if ref == nil {
+		return "", ""
+	}
+	if r == nil {
+		return ref.Kind, ref.Name
+	}
+
+	r.RLock()
+	replicaSets := r.replicaSets
+	jobs := r.jobs
+	synced := r.synced
+	r.RUnlock()
+
+	if !synced {
+		return ref.Kind, ref.Name
+	}
+
+	switch ref.Kind {
+	case "ReplicaSet":
+		if rs, err := replicaSets.ReplicaSets(ns).Get(ref.Name); err == nil {
+			if owner := controllerRef(rs.OwnerReferences); owner != nil {
+				return owner.Kind, owner.Name
+			}
+		}
+	case "Job":
+		if job, err := jobs.Jobs(ns).Get(ref.Name); err == nil {
+			if owner := controllerRef(job.OwnerReferences); owner != nil {
+				return owner.Kind, owner.Name
+			}
+		}
+	}
+
+	return ref.Kind, ref.Name
+}
+
+// controllerRef returns the controller owner reference, or the
+// first owner reference when none is marked as the controller
+func controllerRef(owners []metav1.OwnerReference) *metav1.OwnerReference {
+	for i := range owners {
+		if owners[i].Controller != nil && *owners[i].Controller {
+			return &owners[i]
+		}
+	}
+	if len(owners) > 0 {
+		return &owners[0]
+	}
+	return nil
+}
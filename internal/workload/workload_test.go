@@ -0,0 +1,83 @@
+// Copyright © 2019 Circonus, Inc. <support@circonus.com>
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package workload
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func owner(kind, name string, controller bool) metav1.OwnerReference {
+	return metav1.OwnerReference{Kind: kind, Name: name, Controller: &controller}
+}
+
+func TestResolve(t *testing.T) {
+	indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+
+	rsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+	_ = rsIndexer.Add(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "web-5d8f9",
+		OwnerReferences: []metav1.OwnerReference{owner("Deployment", "web", true)},
+	}})
+	_ = rsIndexer.Add(&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "bare-rs",
+	}})
+
+	jobIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, indexers)
+	_ = jobIndexer.Add(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "backup-1600000000",
+		OwnerReferences: []metav1.OwnerReference{owner("CronJob", "backup", true)},
+	}})
+
+	r := &Resolver{
+		replicaSets: appslisters.NewReplicaSetLister(rsIndexer),
+		jobs:        batchlisters.NewJobLister(jobIndexer),
+		synced:      true,
+	}
+
+	tests := []struct {
+		name   string
+		owners []metav1.OwnerReference
+		kind   string
+		wname  string
+	}{
+		{"no owner", nil, "", ""},
+		{"deployment", []metav1.OwnerReference{owner("ReplicaSet", "web-5d8f9", true)}, "Deployment", "web"},
+		{"bare replicaset", []metav1.OwnerReference{owner("ReplicaSet", "bare-rs", true)}, "ReplicaSet", "bare-rs"},
+		{"unknown replicaset", []metav1.OwnerReference{owner("ReplicaSet", "gone", true)}, "ReplicaSet", "gone"},
+		{"cronjob", []metav1.OwnerReference{owner("Job", "backup-1600000000", true)}, "CronJob", "backup"},
+		{"statefulset", []metav1.OwnerReference{owner("StatefulSet", "db", true)}, "StatefulSet", "db"},
+		{"controller first", []metav1.OwnerReference{owner("Other", "x", false), owner("DaemonSet", "agent", true)}, "DaemonSet", "agent"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			kind, name := r.Resolve("default", tt.owners)
+			if kind != tt.kind || name != tt.wname {
+				t.Fatalf("expected %s/%s, got %s/%s", tt.kind, tt.wname, kind, name)
+			}
+		})
+	}
+
+	t.Log("nil resolver")
+	{
+		var nr *Resolver
+		kind, name := nr.Resolve("default", []metav1.OwnerReference{owner("ReplicaSet", "web-5d8f9", true)})
+		if kind != "ReplicaSet" || name != "web-5d8f9" {
+			t.Fatalf("expected ReplicaSet/web-5d8f9, got %s/%s", kind, name)
+		}
+	}
+}